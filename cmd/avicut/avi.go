@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"main/pkg/avireader"
+	"main/pkg/recorder/encode"
+	"math"
+	"time"
+)
+
+// cutAVI copies the kept frames of the joined inputs into output, with
+// the audio chunks written after them
+// Inputs without audio get silence when others have it, so the joined
+// track stays in sync
+func cutAVI(inputs []string, output string, c cut, noAudio bool) (result, error) {
+	readers := make([]*avireader.Reader, 0, len(inputs))
+	defer func() {
+		for _, r := range readers {
+			r.Close()
+		}
+	}()
+	for _, path := range inputs {
+		r, err := avireader.Open(path)
+		if err != nil {
+			return result{}, fmt.Errorf("%s: %w", path, err)
+		}
+		readers = append(readers, r)
+		fmt.Printf("    Input: %s (%dx%d, %d frames, %.2f fps, %s)\n", path, r.Width, r.Height, r.Len(), r.FPS, r.Duration().Round(time.Millisecond))
+		if r.Truncated {
+			fmt.Printf("    Note: %s was never finalized; using the %d frames it has\n", path, r.Len())
+		}
+	}
+
+	first := readers[0]
+	audioRate := 0
+	for i, r := range readers {
+		if r.Width != first.Width || r.Height != first.Height {
+			return result{}, fmt.Errorf("%s is %dx%d but %s is %dx%d", inputs[i], r.Width, r.Height, inputs[0], first.Width, first.Height)
+		}
+		if math.Abs(r.FPS-first.FPS) > 1e-3 {
+			return result{}, fmt.Errorf("%s is %.2f fps but %s is %.2f fps (convert one with cmd/convert -fps)", inputs[i], r.FPS, inputs[0], first.FPS)
+		}
+		if r.AudioRate == 0 || noAudio {
+			continue
+		}
+		// The writer's audio track is 16-bit stereo, like the recorder's
+		if r.AudioChannels != 2 || r.AudioBits != 16 {
+			return result{}, fmt.Errorf("%s: can't copy %d-bit %d-channel audio (use -no-audio)", inputs[i], r.AudioBits, r.AudioChannels)
+		}
+		if audioRate != 0 && r.AudioRate != audioRate {
+			return result{}, fmt.Errorf("%s: audio is %d Hz, other inputs are %d Hz (use -no-audio)", inputs[i], r.AudioRate, audioRate)
+		}
+		audioRate = r.AudioRate
+	}
+
+	// The joined frames, as (input, frame) pairs
+	type frame struct{ r, i int }
+	var frames []frame
+	var starts []time.Duration
+	for ri, r := range readers {
+		for i := 0; i < r.Len(); i++ {
+			starts = append(starts, first.Time(len(frames)))
+			frames = append(frames, frame{ri, i})
+		}
+	}
+	from, to, err := c.frames(starts)
+	if err != nil {
+		return result{}, err
+	}
+
+	// Audio chunks by the number of frames before them, per input
+	audio := make([][][]avireader.AudioChunk, len(readers))
+	for ri, r := range readers {
+		audio[ri] = make([][]avireader.AudioChunk, r.Len()+1)
+		for _, a := range r.Audio {
+			audio[ri][a.After] = append(audio[ri][a.After], a)
+		}
+	}
+
+	// The source's rate as it's stored, so 29.97 fps stays 30000/1001
+	w, err := encode.NewAVIWriterRate(output, first.Width, first.Height, first.Rate, first.Scale, audioRate)
+	if err != nil {
+		return result{}, err
+	}
+	copyAudio := func(ri, after int) error {
+		if audioRate == 0 {
+			return nil
+		}
+		r := readers[ri]
+		if r.AudioRate == 0 {
+			// A frame's worth of silence, rounded so the total stays right
+			if after == 0 {
+				return nil
+			}
+			samples := int(math.Round(float64(after)*float64(audioRate)/first.FPS)) -
+				int(math.Round(float64(after-1)*float64(audioRate)/first.FPS))
+			return w.AddAudio(make([]byte, samples*4))
+		}
+		for _, a := range audio[ri][after] {
+			pcm, err := r.PCM(a)
+			if err != nil {
+				return err
+			}
+			if err := w.AddAudio(pcm); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for k := from; k < to; k++ {
+		f := frames[k]
+		if f.i == 0 {
+			// Audio written before the input's first frame
+			if err = copyAudio(f.r, 0); err != nil {
+				break
+			}
+		}
+		var data []byte
+		if data, err = readers[f.r].JPEG(f.i); err != nil {
+			break
+		}
+		if err = w.AddFrame(data); err != nil {
+			break
+		}
+		if err = copyAudio(f.r, f.i+1); err != nil {
+			break
+		}
+	}
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return result{}, err
+	}
+	return result{from: from, to: to, total: len(frames), duration: first.Time(to - from)}, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/gif"
+	"os"
+	"time"
+)
+
+// cutGIF copies the kept frames of the joined inputs into output with
+// their palettes, delays and disposal
+// The LZW data is decoded and encoded again, but the palette indexes,
+// and so the pixels, are exactly the same
+func cutGIF(inputs []string, output string, c cut) (result, error) {
+	joined := &gif.GIF{}
+	for i, path := range inputs {
+		g, err := readGIF(path)
+		if err != nil {
+			return result{}, err
+		}
+		fmt.Printf("    Input: %s (%dx%d, %d frames, %s)\n", path, g.Config.Width, g.Config.Height, len(g.Image), gifDuration(g.Delay).Round(time.Millisecond))
+		if i == 0 {
+			joined.Config = g.Config
+			joined.BackgroundIndex = g.BackgroundIndex
+			joined.LoopCount = g.LoopCount
+		} else if g.Config.Width != joined.Config.Width || g.Config.Height != joined.Config.Height {
+			return result{}, fmt.Errorf("%s is %dx%d but %s is %dx%d", path, g.Config.Width, g.Config.Height, inputs[0], joined.Config.Width, joined.Config.Height)
+		}
+		for j, img := range g.Image {
+			joined.Image = append(joined.Image, img)
+			joined.Delay = append(joined.Delay, at(g.Delay, j))
+			joined.Disposal = append(joined.Disposal, at(g.Disposal, j))
+		}
+	}
+
+	starts := make([]time.Duration, len(joined.Image))
+	for i := 1; i < len(starts); i++ {
+		starts[i] = starts[i-1] + time.Duration(joined.Delay[i-1])*10*time.Millisecond
+	}
+	from, to, err := c.frames(starts)
+	if err != nil {
+		return result{}, err
+	}
+	canvas := image.Rect(0, 0, joined.Config.Width, joined.Config.Height)
+	if from > 0 && !joined.Image[from].Bounds().Eq(canvas) {
+		// Frames that only update part of the canvas rely on the ones
+		// before them, which are gone now
+		fmt.Printf("    Note: frame %d doesn't cover the whole canvas, so the start may look incomplete\n", from)
+	}
+
+	out := &gif.GIF{
+		Image:           joined.Image[from:to],
+		Delay:           joined.Delay[from:to],
+		Disposal:        joined.Disposal[from:to],
+		Config:          joined.Config,
+		BackgroundIndex: joined.BackgroundIndex,
+		LoopCount:       joined.LoopCount,
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return result{}, err
+	}
+	defer f.Close()
+	if err := gif.EncodeAll(f, out); err != nil {
+		return result{}, err
+	}
+	if err := f.Close(); err != nil {
+		return result{}, err
+	}
+	return result{from: from, to: to, total: len(joined.Image), duration: gifDuration(out.Delay)}, nil
+}
+
+func readGIF(path string) (*gif.GIF, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	g, err := gif.DecodeAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(g.Image) == 0 {
+		return nil, fmt.Errorf("%s: GIF has no frames", path)
+	}
+	return g, nil
+}
+
+// gifDuration adds up delays in 100ths of a second
+func gifDuration(delays []int) time.Duration {
+	total := 0
+	for _, d := range delays {
+		total += d
+	}
+	return time.Duration(total) * 10 * time.Millisecond
+}
+
+// at returns s[i], or the zero value past the end of s
+func at[T any](s []T, i int) T {
+	var zero T
+	if i < len(s) {
+		return s[i]
+	}
+	return zero
+}
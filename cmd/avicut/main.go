@@ -0,0 +1,151 @@
+// avicut trims and joins recordings without re-encoding them
+//
+//	avicut [flags] -o <output.avi> <input.avi>...
+//	avicut [flags] -o <output.gif> <input.gif>...
+//
+// MJPEG frames are independent JPEGs, so they're copied as stored and
+// only the headers and idx1 index are rebuilt; audio chunks stay with the
+// frames they were written after. GIF frames are copied with their
+// palettes, delays and disposal. Several inputs are joined in order and
+// must have the same frame size (and, for AVIs, frame rate); -start and
+// -end or -from and -to then cut the joined recording
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	output := flag.String("o", "", "output file, .avi or .gif like the inputs")
+	start := flag.Duration("start", 0, "drop the frames before this time")
+	end := flag.Duration("end", 0, "drop the frames from this time on (0 = keep to the end)")
+	from := flag.Int("from", 0, "first frame to keep, counting from 0")
+	to := flag.Int("to", 0, "frame to stop before (0 = keep to the end)")
+	noAudio := flag.Bool("no-audio", false, "drop the AVI audio track")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: avicut [flags] -o <output> <input>...")
+		fmt.Fprintln(os.Stderr, "Example: avicut -start 1s -o flappy-cut.avi recordings/flappy.avi")
+		fmt.Fprintln(os.Stderr, "Example: avicut -o sessions.avi session1.avi session2.avi session3.avi")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 || *output == "" {
+		flag.Usage()
+		return 2
+	}
+	inputs := flag.Args()
+
+	c := cut{start: *start, end: *end, from: *from, to: *to, byFrame: *from != 0 || *to != 0}
+	switch {
+	case c.byFrame && (*start != 0 || *end != 0):
+		fmt.Println("ERROR: use -start/-end or -from/-to, not both")
+		return 2
+	case *start < 0 || *end < 0 || *from < 0 || *to < 0:
+		fmt.Println("ERROR: -start, -end, -from and -to can't be negative")
+		return 2
+	case *end > 0 && *end <= *start:
+		fmt.Println("ERROR: -end must be after -start")
+		return 2
+	case *to > 0 && *to <= *from:
+		fmt.Println("ERROR: -to must be after -from")
+		return 2
+	}
+	if err := checkOutput(*output, inputs); err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return 2
+	}
+
+	if len(inputs) == 1 {
+		fmt.Printf("==> Cutting %s\n", inputs[0])
+	} else {
+		fmt.Printf("==> Joining %d recordings\n", len(inputs))
+	}
+	var res result
+	var err error
+	switch strings.ToLower(filepath.Ext(*output)) {
+	case ".avi":
+		res, err = cutAVI(inputs, *output, c, *noAudio)
+	case ".gif":
+		res, err = cutGIF(inputs, *output, c)
+	default:
+		err = fmt.Errorf("unknown output format %q (want .avi or .gif)", filepath.Ext(*output))
+	}
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		os.Remove(*output)
+		return 1
+	}
+
+	stat, err := os.Stat(*output)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return 1
+	}
+	fmt.Printf("    Kept: frames %d to %d of %d\n", res.from, res.to-1, res.total)
+	fmt.Printf("✓ Saved: %s (%d frames, %s, %.2f MB)\n", *output, res.to-res.from, res.duration.Round(time.Millisecond), float64(stat.Size())/(1<<20))
+	return 0
+}
+
+// result describes what was written
+type result struct {
+	from, to int // kept range of the joined frames
+	total    int
+	duration time.Duration
+}
+
+// cut is the part of the joined recording to keep, by time or by frame
+type cut struct {
+	start, end time.Duration
+	from, to   int
+	byFrame    bool
+}
+
+// frames returns the range [from, to) of frames to keep, given when each
+// frame starts
+// A frame is kept by time if it starts at or after start and before end
+func (c cut) frames(starts []time.Duration) (int, int, error) {
+	n := len(starts)
+	from, to := c.from, c.to
+	if !c.byFrame {
+		// Frame times are rounded down to the nanosecond, so 1s at 30 fps
+		// must still find frame 30
+		const slack = time.Microsecond
+		from = sort.Search(n, func(i int) bool { return starts[i]+slack >= c.start })
+		to = n
+		if c.end > 0 {
+			to = sort.Search(n, func(i int) bool { return starts[i]+slack >= c.end })
+		}
+	} else if to == 0 || to > n {
+		to = n
+	}
+	if from >= to {
+		return 0, 0, errors.New("no frames in the selected range")
+	}
+	return from, to, nil
+}
+
+// checkOutput refuses to write over one of the inputs, which are still
+// being read while the output is written
+func checkOutput(output string, inputs []string) error {
+	out, err := os.Stat(output)
+	if err != nil {
+		return nil
+	}
+	for _, in := range inputs {
+		if stat, err := os.Stat(in); err == nil && os.SameFile(out, stat) {
+			return fmt.Errorf("%s is also an input; write to a new file", output)
+		}
+	}
+	return nil
+}
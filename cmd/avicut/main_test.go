@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// startsAt returns when each of n frames starts at fps, computed as
+// avireader does
+func startsAt(n int, fps float64) []time.Duration {
+	starts := make([]time.Duration, n)
+	for i := range starts {
+		starts[i] = time.Duration(float64(i) / fps * float64(time.Second))
+	}
+	return starts
+}
+
+func TestCutFrames(t *testing.T) {
+	starts := startsAt(90, 30) // 3s
+	tests := []struct {
+		name     string
+		cut      cut
+		from, to int
+	}{
+		{"everything", cut{}, 0, 90},
+		{"from a time", cut{start: time.Second}, 30, 90},
+		{"between times", cut{start: time.Second, end: 2 * time.Second}, 30, 60},
+		{"mid-frame start", cut{start: 1010 * time.Millisecond}, 31, 90},
+		{"end past the last frame", cut{end: time.Minute}, 0, 90},
+		{"by frame", cut{from: 10, to: 20, byFrame: true}, 10, 20},
+		{"by frame to the end", cut{from: 10, byFrame: true}, 10, 90},
+		{"by frame past the end", cut{from: 80, to: 200, byFrame: true}, 80, 90},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, err := tt.cut.frames(starts)
+			if err != nil || from != tt.from || to != tt.to {
+				t.Errorf("frames = [%d, %d), %v; want [%d, %d)", from, to, err, tt.from, tt.to)
+			}
+		})
+	}
+}
+
+func TestCutFramesNonInteger(t *testing.T) {
+	// At 29.97 fps, 1s falls between frames 29 and 30
+	starts := startsAt(90, 30000.0/1001)
+	from, to, err := cut{start: time.Second, end: 2 * time.Second}.frames(starts)
+	if err != nil || from != 30 || to != 60 {
+		t.Errorf("frames = [%d, %d), %v; want [30, 60)", from, to, err)
+	}
+}
+
+func TestCutFramesEmpty(t *testing.T) {
+	starts := startsAt(30, 30)
+	for _, c := range []cut{
+		{start: 2 * time.Second},
+		{start: 500 * time.Millisecond, end: 500 * time.Millisecond},
+		{from: 30, byFrame: true},
+		{from: 10, to: 5, byFrame: true},
+	} {
+		if from, to, err := c.frames(starts); err == nil {
+			t.Errorf("%+v: frames = [%d, %d), want an error", c, from, to)
+		}
+	}
+}
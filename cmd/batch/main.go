@@ -0,0 +1,433 @@
+// batch records many games concurrently with cmd/record
+//
+//	batch [flags] <game dir or glob>...
+//
+// Each game is recorded by its own cmd/record process, -j at a time.
+// The manifest (recordings/manifest.json by default) holds every game's
+// status, run time, frame count, file size and error, and is saved as
+// each game finishes. Running the batch again resumes it: games recorded
+// successfully whose output still exists are skipped, the rest are
+// recorded again. The exit code is 1 if any game failed
+//
+// Per-game settings come from the profile file (see pkg/profile), which
+// is passed on to cmd/record; games it skips are listed in the manifest
+// as skipped with the reason
+//
+// Input scripts (see pkg/bot) drive the whole desktop, not one window, so
+// a game with a script is recorded alone, with no other game running
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"main/pkg/profile"
+	"main/pkg/project"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	jobs := flag.Int("j", max(runtime.NumCPU()/2, 1), "recordings to run at once")
+	duration := flag.Duration("duration", 0, "how long to record each game (default: from the profile, else 10s)")
+	format := flag.String("format", "", "avi, gif or webp (default: from the profile, else avi)")
+	outDir := flag.String("out", "", "output directory (default: recordings in the project)")
+	manifestPath := flag.String("manifest", "", "manifest file (default: <out>/manifest.json)")
+	listPath := flag.String("list", "", "file with a game directory or glob per line, in addition to the arguments")
+	root := flag.String("root", "", "project directory providing pkg/recorder (default: found from the working directory)")
+	force := flag.Bool("force", false, "record every game again, ignoring the manifest")
+	profilesPath := flag.String("profiles", "", "profile file (default: "+profile.DefaultPath+" in the project, if any)")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: batch [flags] <game dir or glob>...")
+		fmt.Fprintln(os.Stderr, "Example: batch -j 4 'ebiten/examples/*'")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	set := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	patterns := flag.Args()
+	if *listPath != "" {
+		lines, err := readList(*listPath)
+		if err != nil {
+			fmt.Printf("ERROR: %v\n", err)
+			return 2
+		}
+		patterns = append(patterns, lines...)
+	}
+	if len(patterns) == 0 {
+		flag.Usage()
+		return 2
+	}
+
+	var err error
+	if *root == "" {
+		if *root, err = project.FindRoot("."); err != nil {
+			fmt.Printf("ERROR: %v or pass -root\n", err)
+			return 2
+		}
+	}
+	if *outDir == "" {
+		*outDir = filepath.Join(*root, "recordings")
+	}
+	if *manifestPath == "" {
+		*manifestPath = filepath.Join(*outDir, "manifest.json")
+	}
+	if *profilesPath == "" {
+		if path := filepath.Join(*root, profile.DefaultPath); fileExists(path) {
+			*profilesPath = path
+		}
+	}
+	var profiles *profile.Profiles
+	if *profilesPath != "" {
+		if profiles, err = profile.Load(*profilesPath); err != nil {
+			fmt.Printf("ERROR: %v\n", err)
+			return 2
+		}
+		if *profilesPath, err = filepath.Abs(*profilesPath); err != nil {
+			fmt.Printf("ERROR: %v\n", err)
+			return 2
+		}
+	}
+	games, err := expandGames(patterns)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return 2
+	}
+	m, err := loadManifest(*manifestPath)
+	if err != nil {
+		fmt.Printf("ERROR: manifest %s: %v\n", *manifestPath, err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// Build cmd/record once rather than once per game
+	work, err := os.MkdirTemp("", "batch-")
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return 1
+	}
+	defer os.RemoveAll(work)
+	recordBin := filepath.Join(work, "record")
+	if runtime.GOOS == "windows" {
+		recordBin += ".exe"
+	}
+	build := exec.CommandContext(ctx, "go", "build", "-o", recordBin, "./cmd/record")
+	build.Dir = *root
+	build.Stdout, build.Stderr = os.Stdout, os.Stderr
+	if err := build.Run(); err != nil {
+		fmt.Printf("ERROR: building cmd/record: %v\n", err)
+		return 1
+	}
+
+	b := &batch{
+		recordBin: recordBin,
+		root:      *root,
+		outDir:    *outDir,
+		work:      work,
+		profiles:  *profilesPath,
+		manifest:  m,
+		total:     len(games),
+	}
+	if set["duration"] {
+		b.duration = *duration
+	}
+	// cmd/record finds the same script for the game by default
+	scripted := func(g game) bool {
+		return profiles.For(g.name).Script != "" || fileExists(filepath.Join(*root, "scripts", "bots", g.name+".json"))
+	}
+	nScripted := 0
+	for i := range games {
+		games[i].scripted = scripted(games[i])
+		if games[i].scripted {
+			nScripted++
+		}
+	}
+	fmt.Printf("==> Recording %d games, %d at a time\n", len(games), *jobs)
+	if nScripted > 0 && *jobs > 1 {
+		fmt.Printf("    Scripted: %d games, each recorded alone since scripts drive the whole desktop\n", nScripted)
+	}
+	fmt.Printf("    Manifest: %s\n", *manifestPath)
+	if *profilesPath != "" {
+		fmt.Printf("    Profiles: %s\n", *profilesPath)
+	}
+
+	sem := make(chan struct{}, max(*jobs, 1))
+	var wg sync.WaitGroup
+	for _, g := range games {
+		prof := profiles.For(g.name)
+		if prof.Skip {
+			b.skip(g, prof.Reason)
+			continue
+		}
+		// Every game has its own format unless -format says otherwise
+		g.format = prof.Format
+		if *format != "" || g.format == "" {
+			g.format = strings.ToLower(*format)
+		}
+		if g.format == "" {
+			g.format = "avi"
+		}
+		if e, ok := m.get(g.name); ok && e.done() && !*force {
+			b.report("SKIP", g.name, "already recorded")
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			b.record(ctx, g)
+		}()
+	}
+	wg.Wait()
+
+	return b.summary()
+}
+
+// game is a game directory to record
+type game struct {
+	name     string
+	dir      string
+	format   string
+	scripted bool // has an input script
+}
+
+// batch runs the recordings and tracks their outcome
+type batch struct {
+	recordBin string
+	root      string
+	outDir    string
+	work      string
+	duration  time.Duration // 0: the profile's
+	profiles  string
+	manifest  *manifest
+	total     int
+
+	// desktop is held alone by a scripted game, whose injected input goes
+	// to whichever window has focus, and shared by the others
+	desktop sync.RWMutex
+
+	mu      sync.Mutex
+	done    int
+	ok      int
+	skipped int
+	failed  []string
+}
+
+// record runs cmd/record for g and stores the outcome in the manifest
+func (b *batch) record(ctx context.Context, g game) {
+	e := entry{
+		Game:   g.name,
+		Dir:    g.dir,
+		Output: filepath.Join(b.outDir, g.name+"."+g.format),
+		Log:    filepath.Join(b.outDir, "logs", g.name+".log"),
+	}
+	resultPath := filepath.Join(b.work, g.name+".result.json")
+	if g.scripted {
+		b.desktop.Lock()
+		defer b.desktop.Unlock()
+	} else {
+		b.desktop.RLock()
+		defer b.desktop.RUnlock()
+	}
+	start := time.Now()
+	err := b.runRecord(ctx, g, e.Output, e.Log, resultPath)
+	e.Duration = time.Since(start)
+	e.Finished = time.Now()
+
+	// cmd/record reports the details; its exit code has the last word
+	var res struct {
+		Frames   int    `json:"frames"`
+		Bytes    int64  `json:"bytes"`
+		ExitCode int    `json:"exit_code"`
+		Error    string `json:"error"`
+	}
+	if data, rerr := os.ReadFile(resultPath); rerr == nil {
+		json.Unmarshal(data, &res)
+	}
+	e.Frames, e.Bytes, e.ExitCode, e.Error = res.Frames, res.Bytes, res.ExitCode, res.Error
+
+	var exit *exec.ExitError
+	switch {
+	case err == nil:
+		e.Status = statusOK
+	case ctx.Err() != nil:
+		e.Status, e.Error = statusInterrupted, "interrupted"
+	default:
+		e.Status = statusFailed
+		if errors.As(err, &exit) {
+			e.ExitCode = exit.ExitCode()
+		}
+		if e.Error == "" {
+			e.Error = err.Error()
+		}
+	}
+	if err := b.manifest.set(e); err != nil {
+		fmt.Printf("ERROR: saving manifest: %v\n", err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.done++
+	switch e.Status {
+	case statusOK:
+		b.ok++
+		b.reportLocked("OK", g.name, fmt.Sprintf("%d frames, %.2f MB, %s", e.Frames, float64(e.Bytes)/(1<<20), e.Duration.Round(time.Second)))
+	case statusFailed:
+		b.failed = append(b.failed, g.name)
+		b.reportLocked("FAILED", g.name, e.Error+" (log: "+e.Log+")")
+	default:
+		b.reportLocked("INTERRUPTED", g.name, "")
+	}
+}
+
+// runRecord runs one cmd/record process with its output in logPath
+func (b *batch) runRecord(ctx context.Context, g game, output, logPath, resultPath string) error {
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return err
+	}
+	log, err := os.Create(logPath)
+	if err != nil {
+		return err
+	}
+	defer log.Close()
+
+	args := []string{"-root", b.root, "-format", g.format, "-output", output, "-result", resultPath}
+	if b.duration > 0 {
+		args = append(args, "-duration", b.duration.String())
+	}
+	if b.profiles != "" {
+		args = append(args, "-profiles", b.profiles)
+	}
+	cmd := exec.CommandContext(ctx, b.recordBin, append(args, g.dir)...)
+	cmd.Stdout, cmd.Stderr = log, log
+	return cmd.Run()
+}
+
+// skip records that g's profile skips it
+func (b *batch) skip(g game, reason string) {
+	e := entry{Game: g.name, Dir: g.dir, Status: statusSkipped, Error: reason, Finished: time.Now()}
+	if err := b.manifest.set(e); err != nil {
+		fmt.Printf("ERROR: saving manifest: %v\n", err)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.done++
+	b.skipped++
+	b.reportLocked("SKIP", g.name, reason)
+}
+
+// report prints a progress line
+func (b *batch) report(status, name, detail string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.done++
+	b.reportLocked(status, name, detail)
+}
+
+func (b *batch) reportLocked(status, name, detail string) {
+	line := fmt.Sprintf("[%d/%d] %s: %s", b.done, b.total, status, name)
+	if detail != "" {
+		line += " - " + detail
+	}
+	fmt.Println(line)
+}
+
+// summary prints the totals and returns the exit code
+func (b *batch) summary() int {
+	fmt.Println()
+	fmt.Println("==> Batch recording complete!")
+	fmt.Printf("    Recorded: %d\n", b.ok)
+	if b.skipped > 0 {
+		fmt.Printf("    Skipped by profile: %d\n", b.skipped)
+	}
+	fmt.Printf("    Total games: %d\n", b.total)
+	if len(b.failed) == 0 {
+		return 0
+	}
+	sort.Strings(b.failed)
+	fmt.Printf("    Failed (%d): %s\n", len(b.failed), strings.Join(b.failed, ", "))
+	return 1
+}
+
+// expandGames resolves directories and globs to game directories, ones
+// with .go files, in name order
+func expandGames(patterns []string) ([]game, error) {
+	seen := map[string]string{}
+	var games []game
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no game matches %s", pattern)
+		}
+		for _, dir := range matches {
+			if goFiles, _ := filepath.Glob(filepath.Join(dir, "*.go")); len(goFiles) == 0 {
+				continue
+			}
+			name := filepath.Base(dir)
+			if prev, ok := seen[name]; ok {
+				if prev == dir {
+					continue
+				}
+				return nil, fmt.Errorf("two games named %s: %s and %s", name, prev, dir)
+			}
+			seen[name] = dir
+			games = append(games, game{name: name, dir: dir})
+		}
+	}
+	if len(games) == 0 {
+		return nil, errors.New("no game directories found")
+	}
+	sort.Slice(games, func(i, j int) bool { return games[i].name < games[j].name })
+	return games, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// readList reads a list file: one entry per line, # starts a comment
+func readList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, sc.Err()
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Game statuses in the manifest
+const (
+	statusOK          = "ok"
+	statusFailed      = "failed"
+	statusInterrupted = "interrupted"
+	statusSkipped     = "skipped" // by its profile; Error has the reason
+)
+
+// entry is one game's line in the manifest
+// Durations are serialized as nanoseconds, like the stats sidecars
+type entry struct {
+	Game     string        `json:"game"`
+	Dir      string        `json:"dir"`
+	Status   string        `json:"status"`
+	Output   string        `json:"output,omitempty"`
+	Duration time.Duration `json:"duration_ns"` // wall time of the recording run
+	Frames   int           `json:"frames"`
+	Bytes    int64         `json:"bytes"`
+	ExitCode int           `json:"exit_code"`
+	Error    string        `json:"error,omitempty"`
+	Log      string        `json:"log,omitempty"`
+	Finished time.Time     `json:"finished"`
+}
+
+// done reports whether the game was recorded and its output is still there
+func (e entry) done() bool {
+	if e.Status != statusOK {
+		return false
+	}
+	_, err := os.Stat(e.Output)
+	return err == nil
+}
+
+// manifest is the batch's record of every game, saved after each one
+// finishes so an interrupted batch can resume
+type manifest struct {
+	path  string
+	mu    sync.Mutex
+	games map[string]entry
+}
+
+// loadManifest reads the manifest at path; a missing file is an empty one
+func loadManifest(path string) (*manifest, error) {
+	m := &manifest{path: path, games: map[string]entry{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Games []entry `json:"games"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	for _, e := range file.Games {
+		m.games[e.Game] = e
+	}
+	return m, nil
+}
+
+func (m *manifest) get(game string) (entry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.games[game]
+	return e, ok
+}
+
+// set records e and saves the manifest
+func (m *manifest) set(e entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.games[e.Game] = e
+	return m.save()
+}
+
+// save writes the manifest through a temporary file, so a crash can't
+// leave it half written
+func (m *manifest) save() error {
+	games := make([]entry, 0, len(m.games))
+	for _, e := range m.games {
+		games = append(games, e)
+	}
+	sort.Slice(games, func(i, j int) bool { return games[i].Game < games[j].Game })
+
+	data, err := json.MarshalIndent(struct {
+		Updated time.Time `json:"updated"`
+		Games   []entry   `json:"games"`
+	}{time.Now(), games}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return err
+	}
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.path)
+}
@@ -0,0 +1,277 @@
+// convert turns a recording into another format, e.g. an AVI into a GIF
+// for a README
+//
+//	convert [flags] -o <output.avi|.gif|.webp> <input>
+//
+// The input is an AVI, GIF or WebP file, or a PNG sequence given as a
+// directory or glob. It's written with the recorders' own encoders
+// (pkg/recorder/encode), so no ffmpeg is needed. Audio isn't carried over
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"io"
+	"main/pkg/recorder/encode"
+	"main/pkg/recording"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/image/draw"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	output := flag.String("o", "", "output file; its extension picks the format (avi, gif or webp)")
+	start := flag.Duration("start", 0, "skip the input before this time")
+	end := flag.Duration("end", 0, "stop at this time in the input (0 = the end)")
+	fps := flag.Float64("fps", 0, "output frame rate (default: the input's)")
+	scale := flag.Float64("scale", 0, "scale frames by this factor, e.g. 0.5")
+	width := flag.Int("width", 0, "scale frames to this width, keeping the aspect ratio")
+	quality := flag.Int("quality", 85, "JPEG quality for AVI (1-100)")
+	pal := flag.String("palette", "plan9", "GIF and WebP palette: plan9 (as recorded), websafe, adaptive, or none (WebP only, full color)")
+	colors := flag.Int("colors", 256, "colors in an adaptive palette (2-256)")
+	dither := flag.Bool("dither", false, "Floyd-Steinberg dithering when reducing to the palette")
+	inFPS := flag.Float64("in-fps", 30, "frame rate of a PNG sequence input")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: convert [flags] -o <output> <input file, PNG directory or glob>")
+		fmt.Fprintln(os.Stderr, "Example: convert -start 1s -end 6s -width 320 -palette adaptive -o flappy.gif recordings/flappy.avi")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 || *output == "" {
+		flag.Usage()
+		return 2
+	}
+	input := flag.Arg(0)
+
+	format := strings.ToLower(strings.TrimPrefix(filepath.Ext(*output), "."))
+	switch {
+	case format != "avi" && format != "gif" && format != "webp":
+		fmt.Printf("ERROR: unknown output format %q (want .avi, .gif or .webp)\n", filepath.Ext(*output))
+		return 2
+	case *end > 0 && *end <= *start:
+		fmt.Println("ERROR: -end must be after -start")
+		return 2
+	case *scale < 0 || *width < 0 || *fps < 0:
+		fmt.Println("ERROR: -scale, -width and -fps can't be negative")
+		return 2
+	case *pal == "none" && format == "gif":
+		fmt.Println("ERROR: GIF needs a palette")
+		return 2
+	}
+
+	c := &converter{input: input, inFPS: *inFPS, start: *start, end: *end, fps: *fps, scale: *scale, width: *width}
+	src, err := openSource(input, *inFPS)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return 1
+	}
+	if c.fps == 0 {
+		c.fps = src.FPS()
+	}
+
+	var out sink
+	if format == "avi" {
+		// AVI frame rates are whole numbers here, like the recorders'
+		c.fps = max(math.Round(c.fps), 1)
+		out = &aviSink{path: *output, fps: int(c.fps), quality: *quality}
+	} else {
+		p, err := c.palette(*pal, *colors)
+		if err != nil {
+			src.Close()
+			fmt.Printf("ERROR: %v\n", err)
+			return 2
+		}
+		out = &animSink{path: *output, webp: format == "webp", fps: c.fps, pal: p, dither: *dither}
+	}
+
+	fmt.Printf("==> Converting %s to %s\n", input, *output)
+	n, err := c.run(src, out)
+	src.Close()
+	if err == nil && n == 0 {
+		err = errors.New("no frames in the selected range")
+	}
+	if err != nil {
+		out.discard()
+		fmt.Printf("ERROR: %v\n", err)
+		return 1
+	}
+	if err := out.close(); err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		os.Remove(*output)
+		return 1
+	}
+	stat, err := os.Stat(*output)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return 1
+	}
+	fmt.Printf("✓ Saved: %s (%d frames at %.2f fps, %.2f MB)\n", *output, n, c.fps, float64(stat.Size())/(1<<20))
+	return 0
+}
+
+// openSource opens a recording, or a PNG sequence from a directory or
+// glob shown at pngFPS
+func openSource(path string, pngFPS float64) (recording.Source, error) {
+	if strings.ContainsAny(path, "*?[") {
+		files, err := filepath.Glob(path)
+		if err != nil {
+			return nil, err
+		}
+		return recording.OpenPNGs(files, pngFPS)
+	}
+	if stat, err := os.Stat(path); err == nil && stat.IsDir() {
+		files, _ := filepath.Glob(filepath.Join(path, "*.png"))
+		return recording.OpenPNGs(files, pngFPS)
+	}
+	return recording.Open(path)
+}
+
+// converter trims, resamples and scales frames on their way to a sink
+type converter struct {
+	input      string
+	inFPS      float64
+	start, end time.Duration
+	fps        float64
+	scale      float64
+	width      int
+}
+
+// run feeds src's frames between start and end to out at c.fps and
+// returns how many were written
+// Output frame k shows whichever input frame is on screen at
+// start + k/fps, so frames are repeated or dropped to change the rate
+func (c *converter) run(src recording.Source, out sink) (int, error) {
+	if err := src.SkipTo(c.start); err != nil {
+		return 0, err
+	}
+	n := 0
+	for {
+		img, t, dur, err := src.Next()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		if c.end > 0 && t >= c.end {
+			return n, nil
+		}
+		var scaled image.Image
+		for {
+			at := c.start + time.Duration(float64(n)/c.fps*float64(time.Second))
+			if at >= t+dur || (c.end > 0 && at >= c.end) {
+				break
+			}
+			if scaled == nil {
+				scaled = c.resize(img)
+			}
+			if err := out.add(scaled); err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
+}
+
+// resize applies -scale or -width
+// Whole upscale factors use nearest-neighbour so pixel art stays sharp,
+// like the recorder's Transform; anything else uses Catmull-Rom
+func (c *converter) resize(img image.Image) image.Image {
+	b := img.Bounds()
+	factor := c.scale
+	if c.width > 0 {
+		factor = float64(c.width) / float64(b.Dx())
+	}
+	if factor == 0 || factor == 1 {
+		return img
+	}
+	w := max(int(math.Round(float64(b.Dx())*factor)), 1)
+	h := max(int(math.Round(float64(b.Dy())*factor)), 1)
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	var scaler draw.Interpolator = draw.CatmullRom
+	if factor > 1 && factor == math.Trunc(factor) {
+		scaler = draw.NearestNeighbor
+	}
+	scaler.Scale(dst, dst.Rect, img, b, draw.Src, nil)
+	return dst
+}
+
+// palette returns the palette for -palette, nil for none
+// An adaptive palette takes a first pass over the input to sample frames
+func (c *converter) palette(name string, colors int) (color.Palette, error) {
+	switch name {
+	case "plan9":
+		return palette.Plan9, nil
+	case "websafe":
+		return palette.WebSafe, nil
+	case "none":
+		return nil, nil
+	case "adaptive":
+	default:
+		return nil, fmt.Errorf("unknown palette %q (want plan9, websafe, adaptive or none)", name)
+	}
+
+	src, err := openSource(c.input, c.inFPS)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	samples := &sampler{max: 32}
+	if _, err := c.run(src, samples); err != nil {
+		return nil, err
+	}
+	if len(samples.frames) == 0 {
+		return nil, errors.New("no frames in the selected range")
+	}
+	return encode.AdaptivePalette(samples.frames, colors), nil
+}
+
+// sampler is a sink that keeps up to max frames spread evenly over the
+// whole input: when full it drops every other frame and keeps only
+// every second one from then on
+type sampler struct {
+	max    int
+	frames []image.Image
+	every  int
+	seen   int
+}
+
+func (s *sampler) add(img image.Image) error {
+	if s.every == 0 {
+		s.every = 1
+	}
+	s.seen++
+	if (s.seen-1)%s.every != 0 {
+		return nil
+	}
+	// Sources reuse their images
+	b := img.Bounds()
+	frame := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Copy(frame, image.Point{}, img, b, draw.Src, nil)
+	s.frames = append(s.frames, frame)
+	if len(s.frames) == s.max {
+		kept := s.frames[:0]
+		for i := 0; i < len(s.frames); i += 2 {
+			kept = append(kept, s.frames[i])
+		}
+		s.frames = kept
+		s.every *= 2
+	}
+	return nil
+}
+
+func (s *sampler) close() error { return nil }
+
+func (s *sampler) discard() {}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"main/pkg/recorder/encode"
+	"math"
+	"os"
+)
+
+// sink writes the output recording
+type sink interface {
+	add(img image.Image) error
+	close() error
+
+	// discard abandons the output, removing anything already written
+	discard()
+}
+
+// aviSink writes an MJPEG AVI with the recorders' AVI writer, a frame
+// at a time
+type aviSink struct {
+	path    string
+	fps     int
+	quality int
+	w       *encode.AVIWriter
+}
+
+func (s *aviSink) add(img image.Image) error {
+	if s.w == nil {
+		b := img.Bounds()
+		w, err := encode.NewAVIWriter(s.path, b.Dx(), b.Dy(), s.fps, 0)
+		if err != nil {
+			return err
+		}
+		s.w = w
+	}
+	frame, err := encode.JPEG(img, s.quality)
+	if err != nil {
+		return err
+	}
+	return s.w.AddFrame(frame)
+}
+
+func (s *aviSink) close() error {
+	if s.w == nil {
+		return nil
+	}
+	return s.w.Close()
+}
+
+func (s *aviSink) discard() {
+	if s.w != nil {
+		s.w.Close()
+		os.Remove(s.path)
+	}
+}
+
+// animSink collects frames for a GIF or WebP, which are encoded in one
+// go like the recorders do
+// Frames are kept paletted, a byte per pixel, unless pal is nil (WebP
+// only), which keeps full color
+type animSink struct {
+	path   string
+	webp   bool
+	fps    float64
+	pal    color.Palette
+	dither bool
+	frames []image.Image
+}
+
+func (s *animSink) add(img image.Image) error {
+	if s.pal != nil {
+		s.frames = append(s.frames, encode.Paletted(img, s.pal, s.dither))
+		return nil
+	}
+	// Sources reuse their images
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Rect, img, b.Min, draw.Src)
+	s.frames = append(s.frames, rgba)
+	return nil
+}
+
+// discard drops the frames; nothing is written before close
+func (s *animSink) discard() {
+	s.frames = nil
+}
+
+func (s *animSink) close() error {
+	if len(s.frames) == 0 {
+		return nil
+	}
+	f, err := os.Create(s.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if s.webp {
+		durations := make([]uint, len(s.frames))
+		for i := range durations {
+			durations[i] = uint(frameTicks(i, s.fps, 1000))
+		}
+		err = encode.WriteWebP(f, s.frames, durations)
+	} else {
+		frames := make([]*image.Paletted, len(s.frames))
+		delays := make([]int, len(s.frames))
+		for i, img := range s.frames {
+			frames[i] = img.(*image.Paletted)
+			delays[i] = frameTicks(i, s.fps, 100)
+		}
+		err = encode.WriteGIF(f, frames, delays)
+	}
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// frameTicks returns frame i's duration in 1/perSecond ticks at fps
+// Rounding the frame's end rather than its length keeps the total
+// right, e.g. 30 fps in GIF delays is 3, 4, 3, 3, 4, 3...
+func frameTicks(i int, fps float64, perSecond float64) int {
+	end := math.Round(float64(i+1) * perSecond / fps)
+	start := math.Round(float64(i) * perSecond / fps)
+	return max(int(end-start), 1)
+}
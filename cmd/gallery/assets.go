@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"main/pkg/recorder/encode"
+	"main/pkg/recording"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// assetsDir holds the thumbnails and previews, inside the output
+const assetsDir = "assets"
+
+// thumbnailCandidates is how many frames the thumbnail is picked from
+const thumbnailCandidates = 60
+
+// assetInfo is what's learned from a recording, saved with its assets so
+// an unchanged recording isn't read again
+type assetInfo struct {
+	Duration    time.Duration `json:"duration_ns"`
+	FPS         float64       `json:"fps"`
+	Width       int           `json:"width"`
+	Height      int           `json:"height"`
+	ThumbnailAt time.Duration `json:"thumbnail_at_ns"`
+}
+
+// gallery makes the assets and tracks progress
+type gallery struct {
+	outDir     string
+	force      bool
+	width      int
+	preview    time.Duration
+	previewFPS float64
+	total      int
+
+	mu     sync.Mutex
+	done   int
+	failed int
+}
+
+// asset returns the path of a game's asset with extension ext, e.g.
+// ".jpg"
+func (g *gallery) asset(name, ext string) string {
+	return filepath.Join(g.outDir, assetsDir, name+ext)
+}
+
+// process fills in gm from its recording, making its thumbnail and
+// preview if they're missing or older than the recording
+func (g *gallery) process(gm *game) {
+	stat, err := os.Stat(gm.Path)
+	if err == nil {
+		gm.Size = stat.Size()
+		gm.Problems = readProblems(gm.Path)
+		err = g.assets(gm, stat.ModTime())
+	}
+	if err != nil {
+		gm.Err = err.Error()
+		g.mu.Lock()
+		g.failed++
+		g.mu.Unlock()
+		g.report(gm, "ERROR: "+gm.Err)
+		return
+	}
+	g.report(gm, fmt.Sprintf("%s, %.2f MB", gm.Info.Duration.Round(100*time.Millisecond), float64(gm.Size)/(1<<20)))
+}
+
+func (g *gallery) assets(gm *game, modified time.Time) error {
+	infoPath := g.asset(gm.Name, ".json")
+	if !g.force && fileExists(g.asset(gm.Name, ".jpg")) && fileExists(g.asset(gm.Name, ".webp")) {
+		if stat, err := os.Stat(infoPath); err == nil && stat.ModTime().After(modified) {
+			if data, err := os.ReadFile(infoPath); err == nil && json.Unmarshal(data, &gm.Info) == nil {
+				return nil
+			}
+		}
+	}
+
+	// Sources only go forward, so the preview reads the file again
+	src, err := recording.Open(gm.Path)
+	if err != nil {
+		return err
+	}
+	thumb, at, _, err := recording.Pick(src, thumbnailCandidates)
+	info := assetInfo{Duration: src.Duration(), FPS: src.FPS(), ThumbnailAt: at}
+	src.Close()
+	if err != nil {
+		return err
+	}
+	info.Width, info.Height = thumb.Bounds().Dx(), thumb.Bounds().Dy()
+	jpeg, err := encode.JPEG(recording.Scale(thumb, g.width), 85)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(g.asset(gm.Name, ".jpg"), jpeg, 0644); err != nil {
+		return err
+	}
+	if err := g.makePreview(gm, info); err != nil {
+		return err
+	}
+
+	gm.Info = info
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(infoPath, data, 0644)
+}
+
+// makePreview writes an animated WebP of the part of the recording
+// around the thumbnail, at the preview's size and frame rate, with a
+// palette picked for it
+func (g *gallery) makePreview(gm *game, info assetInfo) error {
+	src, err := recording.Open(gm.Path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	length := min(g.preview, info.Duration)
+	start := min(max(info.ThumbnailAt-length/2, 0), info.Duration-length)
+	var frames []image.Image
+	var frame image.Image
+	shownUntil := time.Duration(-1)
+	for k := 0; ; k++ {
+		t := start + time.Duration(float64(k)/g.previewFPS*float64(time.Second))
+		if t >= start+length {
+			break
+		}
+		if t >= shownUntil {
+			if err := src.SkipTo(t); err != nil {
+				return err
+			}
+			img, at, dur, err := src.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			frame, shownUntil = recording.Scale(img, g.width), at+dur
+		}
+		frames = append(frames, frame)
+	}
+	if len(frames) == 0 {
+		return errors.New("no frames for the preview")
+	}
+
+	pal := encode.AdaptivePalette(frames, 256)
+	durations := make([]uint, len(frames))
+	for i := range frames {
+		frames[i] = encode.Paletted(frames[i], pal, false)
+		// Rounding each frame's end keeps the total right
+		end := math.Round(float64(i+1) * 1000 / g.previewFPS)
+		durations[i] = uint(max(end-math.Round(float64(i)*1000/g.previewFPS), 1))
+	}
+	f, err := os.Create(g.asset(gm.Name, ".webp"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := encode.WriteWebP(f, frames, durations); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// readProblems returns the problems the recording's stats sidecar
+// flagged, if it has one
+func readProblems(path string) []string {
+	data, err := os.ReadFile(path + ".stats.json")
+	if err != nil {
+		return nil
+	}
+	var stats struct {
+		Problems []string `json:"problems"`
+	}
+	json.Unmarshal(data, &stats)
+	return stats.Problems
+}
+
+// report prints a progress line
+func (g *gallery) report(gm *game, detail string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.done++
+	fmt.Printf("[%d/%d] %s: %s - %s\n", g.done, g.total, strings.ToUpper(gm.Status), gm.Name, detail)
+}
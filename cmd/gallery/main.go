@@ -0,0 +1,262 @@
+// gallery builds a static HTML page for reviewing a batch of recordings
+//
+//	gallery [flags] [recordings dir]
+//
+// Every game in the batch manifest, and every other recording in the
+// directory, gets a card with a thumbnail, an animated WebP preview, its
+// length, file size and status, the problems its stats sidecar flagged,
+// and links to the recording, the batch log and the example's source.
+// Failed and skipped games are listed with the error or the reason
+//
+// The site is index.html and an assets directory, in <dir>/gallery by
+// default, and needs no server or network. A recording's assets are only
+// made again when it changes
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"main/pkg/project"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Game statuses, as cmd/batch writes them in the manifest
+const (
+	statusOK          = "ok"
+	statusFailed      = "failed"
+	statusInterrupted = "interrupted"
+	statusSkipped     = "skipped"
+
+	// statusUnlisted is a recording the manifest doesn't mention, e.g.
+	// one made with cmd/record
+	statusUnlisted = "unlisted"
+)
+
+// recordingFormats are the extensions looked for, in order of preference
+var recordingFormats = []string{".avi", ".webp", ".gif"}
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	outDir := flag.String("out", "", "output directory (default: <dir>/gallery)")
+	manifestPath := flag.String("manifest", "", "batch manifest (default: <dir>/manifest.json)")
+	jobs := flag.Int("j", max(runtime.NumCPU()/2, 1), "recordings to process at once")
+	force := flag.Bool("force", false, "make every thumbnail and preview again")
+	width := flag.Int("width", 320, "thumbnail and preview width")
+	preview := flag.Duration("preview", 6*time.Second, "length of the animated preview")
+	previewFPS := flag.Float64("preview-fps", 10, "frame rate of the animated preview")
+	source := flag.String("source", "", "source link with {game} for the game's name (default: from the examples' git checkout)")
+	title := flag.String("title", "Recordings", "page title")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: gallery [flags] [recordings dir]")
+		fmt.Fprintln(os.Stderr, "Example: gallery -source 'https://github.com/hajimehoshi/ebiten/tree/main/examples/{game}' recordings")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() > 1 {
+		flag.Usage()
+		return 2
+	}
+	if *width < 1 || *preview <= 0 || *previewFPS <= 0 {
+		fmt.Println("ERROR: -width, -preview and -preview-fps must be positive")
+		return 2
+	}
+
+	dir := flag.Arg(0)
+	if dir == "" {
+		root, err := project.FindRoot(".")
+		if err != nil {
+			fmt.Printf("ERROR: %v or pass the recordings dir\n", err)
+			return 2
+		}
+		dir = filepath.Join(root, "recordings")
+	}
+	if *outDir == "" {
+		*outDir = filepath.Join(dir, "gallery")
+	}
+	if *manifestPath == "" {
+		*manifestPath = filepath.Join(dir, "manifest.json")
+	}
+
+	games, err := collect(dir, *manifestPath)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return 1
+	}
+	if len(games) == 0 {
+		fmt.Printf("ERROR: no recordings in %s and no manifest at %s\n", dir, *manifestPath)
+		return 1
+	}
+	if err := os.MkdirAll(filepath.Join(*outDir, assetsDir), 0755); err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return 1
+	}
+
+	g := &gallery{
+		outDir:     *outDir,
+		force:      *force,
+		width:      *width,
+		preview:    *preview,
+		previewFPS: *previewFPS,
+		total:      len(games),
+	}
+	fmt.Printf("==> Building gallery of %d games in %s\n", len(games), *outDir)
+	sem := make(chan struct{}, max(*jobs, 1))
+	var wg sync.WaitGroup
+	for _, gm := range games {
+		if gm.Path == "" {
+			g.report(gm, "no recording")
+			continue
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			g.process(gm)
+		}()
+	}
+	wg.Wait()
+
+	links := &sourceLinks{template: *source, outDir: *outDir, repos: map[string]*repo{}}
+	for _, gm := range games {
+		gm.Source = links.link(gm.Name, gm.Dir)
+	}
+	index := filepath.Join(*outDir, "index.html")
+	if err := writePage(index, *title, *outDir, games); err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return 1
+	}
+
+	counts := map[string]int{}
+	for _, gm := range games {
+		counts[gm.Status]++
+	}
+	fmt.Println()
+	fmt.Println("==> Gallery complete!")
+	fmt.Printf("    Recorded: %d\n", counts[statusOK])
+	for _, status := range []string{statusFailed, statusInterrupted, statusSkipped, statusUnlisted} {
+		if counts[status] > 0 {
+			fmt.Printf("    %s: %d\n", strings.ToUpper(status[:1])+status[1:], counts[status])
+		}
+	}
+	if g.failed > 0 {
+		fmt.Printf("    Unreadable recordings: %d\n", g.failed)
+	}
+	fmt.Printf("✓ Saved: %s\n", index)
+	return 0
+}
+
+// game is one card of the gallery
+type game struct {
+	Name     string
+	Status   string
+	Reason   string // the error, or why the profile skips the game
+	Dir      string // the example's directory, from the manifest
+	Path     string // the recording, if there is one
+	Log      string
+	Frames   int
+	Finished time.Time
+
+	// From the recording and its stats sidecar
+	Size     int64
+	Info     assetInfo
+	Problems []string
+	Err      string // the recording couldn't be read
+
+	Source string // link to the example's source
+}
+
+// manifestEntry is the part of a cmd/batch manifest entry the gallery
+// shows
+type manifestEntry struct {
+	Game     string    `json:"game"`
+	Dir      string    `json:"dir"`
+	Status   string    `json:"status"`
+	Output   string    `json:"output"`
+	Frames   int       `json:"frames"`
+	Error    string    `json:"error"`
+	Log      string    `json:"log"`
+	Finished time.Time `json:"finished"`
+}
+
+// collect returns the manifest's games and the recordings in dir it
+// doesn't mention, by name
+// A missing manifest is an empty one
+func collect(dir, manifestPath string) ([]*game, error) {
+	byName := map[string]*game{}
+	data, err := os.ReadFile(manifestPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		var file struct {
+			Games []manifestEntry `json:"games"`
+		}
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("manifest %s: %w", manifestPath, err)
+		}
+		for _, e := range file.Games {
+			g := &game{Name: e.Game, Status: e.Status, Reason: e.Error, Dir: e.Dir, Log: e.Log, Frames: e.Frames, Finished: e.Finished}
+			if fileExists(e.Output) {
+				g.Path = e.Output
+			} else {
+				// The manifest may have moved with the recordings
+				g.Path = findRecording(dir, e.Game)
+			}
+			if e.Log != "" && !fileExists(e.Log) {
+				if log := filepath.Join(dir, "logs", e.Game+".log"); fileExists(log) {
+					g.Log = log
+				} else {
+					g.Log = ""
+				}
+			}
+			byName[e.Game] = g
+		}
+	}
+
+	for _, ext := range recordingFormats {
+		files, _ := filepath.Glob(filepath.Join(dir, "*"+ext))
+		for _, path := range files {
+			name := strings.TrimSuffix(filepath.Base(path), ext)
+			if _, ok := byName[name]; !ok {
+				byName[name] = &game{Name: name, Status: statusUnlisted, Path: path}
+			}
+		}
+	}
+
+	games := make([]*game, 0, len(byName))
+	for _, g := range byName {
+		games = append(games, g)
+	}
+	sort.Slice(games, func(i, j int) bool { return games[i].Name < games[j].Name })
+	return games, nil
+}
+
+// findRecording returns the recording of game in dir, or ""
+func findRecording(dir, game string) string {
+	for _, ext := range recordingFormats {
+		if path := filepath.Join(dir, game+ext); fileExists(path) {
+			return path
+		}
+	}
+	return ""
+}
+
+func fileExists(path string) bool {
+	if path == "" {
+		return false
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
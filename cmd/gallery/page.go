@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// card is a game with the links its card needs, relative to the page
+type card struct {
+	*game
+	Recording string
+	Thumbnail string
+	Preview   string
+	LogLink   string
+}
+
+// statusCount is a line of the page's summary
+type statusCount struct {
+	Status string
+	Count  int
+}
+
+// writePage writes the gallery's index.html to path
+func writePage(path, title, outDir string, games []*game) error {
+	data := struct {
+		Title     string
+		Generated time.Time
+		Summary   []statusCount
+		Size      int64
+		Cards     []card
+	}{Title: title, Generated: time.Now()}
+
+	counts := map[string]int{}
+	for _, g := range games {
+		counts[g.Status]++
+		data.Size += g.Size
+		c := card{game: g}
+		if g.Log != "" {
+			c.LogLink = relLink(outDir, g.Log)
+		}
+		if g.Path != "" {
+			c.Recording = relLink(outDir, g.Path)
+			if g.Err == "" {
+				c.Thumbnail = assetsDir + "/" + g.Name + ".jpg"
+				c.Preview = assetsDir + "/" + g.Name + ".webp"
+			}
+		}
+		data.Cards = append(data.Cards, c)
+	}
+	for _, status := range []string{statusOK, statusFailed, statusInterrupted, statusSkipped, statusUnlisted} {
+		if counts[status] > 0 {
+			data.Summary = append(data.Summary, statusCount{status, counts[status]})
+		}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := pageTemplate.Execute(f, data); err != nil {
+		return fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return f.Close()
+}
+
+var pageTemplate = template.Must(template.New("page").Funcs(template.FuncMap{
+	"mb": func(n int64) string { return fmt.Sprintf("%.2f MB", float64(n)/(1<<20)) },
+	"seconds": func(d time.Duration) string {
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { margin: 0; padding: 16px 24px; background: #1b1b1b; color: #ddd; font: 14px/1.4 system-ui, sans-serif; }
+a { color: #8cf; }
+h1 { margin: 0 0 4px; font-size: 22px; }
+.summary { margin: 0 0 16px; color: #aaa; }
+.summary span { margin-right: 12px; }
+.grid { display: grid; grid-template-columns: repeat(auto-fill, minmax(320px, 1fr)); gap: 16px; }
+.card { background: #262626; border-radius: 6px; overflow: hidden; }
+.media { position: relative; aspect-ratio: 4 / 3; background: #111; display: flex; align-items: center; justify-content: center; color: #666; }
+.media img { position: absolute; inset: 0; width: 100%; height: 100%; object-fit: contain; image-rendering: pixelated; }
+.media .preview { display: none; }
+.card:hover .media .preview { display: block; }
+.body { padding: 8px 12px 12px; }
+.name { font-size: 16px; font-weight: 600; margin-right: 6px; }
+.status { display: inline-block; padding: 0 6px; border-radius: 3px; font-size: 12px; text-transform: uppercase; color: #111; }
+.status-ok { background: #6c6; }
+.status-failed { background: #e66; }
+.status-interrupted { background: #eb5; }
+.status-skipped { background: #999; }
+.status-unlisted { background: #8cf; }
+.facts { margin: 6px 0; color: #aaa; }
+.problems { margin: 6px 0; padding-left: 18px; color: #eb5; }
+.reason { margin: 6px 0; padding: 0; list-style: none; color: #e99; }
+.links a { margin-right: 10px; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="summary">
+{{- range .Summary}}<span><span class="status status-{{.Status}}">{{.Status}}</span> {{.Count}}</span>{{end -}}
+<span>{{mb .Size}} in all</span><span>Generated {{.Generated.Format "2006-01-02 15:04"}}</span>
+</p>
+<p class="summary">Hover over a card to play its preview.</p>
+<div class="grid">
+{{- range .Cards}}
+<div class="card" id="{{.Name}}">
+  <div class="media">
+    {{- if .Thumbnail}}
+    <img class="thumbnail" src="{{.Thumbnail}}" alt="{{.Name}}" loading="lazy">
+    <img class="preview" src="{{.Preview}}" alt="" loading="lazy">
+    {{- else if .Err}}unreadable recording
+    {{- else}}no recording{{end}}
+  </div>
+  <div class="body">
+    <span class="name">{{.Name}}</span><span class="status status-{{.Status}}">{{.Status}}</span>
+    {{- if .Recording}}
+    <div class="facts">
+      {{- if .Err}}{{mb .Size}}{{else}}{{seconds .Info.Duration}}, {{.Info.Width}}x{{.Info.Height}}, {{printf "%.0f" .Info.FPS}} fps, {{mb .Size}}{{end -}}
+      {{- if .Frames}}, {{.Frames}} frames{{end -}}
+      {{- if not .Finished.IsZero}}<br>Recorded {{.Finished.Format "2006-01-02 15:04"}}{{end -}}
+    </div>
+    {{- end}}
+    {{- if .Reason}}
+    <ul class="reason"><li>{{.Reason}}</li></ul>
+    {{- end}}
+    {{- if .Err}}
+    <ul class="reason"><li>{{.Err}}</li></ul>
+    {{- end}}
+    {{- if .Problems}}
+    <ul class="problems">{{range .Problems}}<li>{{.}}</li>{{end}}</ul>
+    {{- end}}
+    <div class="links">
+      {{- if .Recording}}<a href="{{.Recording}}">Recording</a>{{end -}}
+      {{- if .LogLink}}<a href="{{.LogLink}}">Log</a>{{end -}}
+      {{- if .Source}}<a href="{{.Source}}">Source</a>{{end -}}
+    </div>
+  </div>
+</div>
+{{- end}}
+</div>
+</body>
+</html>
+`))
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// sourceLinks turns example directories into links to their source
+// With a template, {game} is replaced by the game's name. Otherwise a
+// directory in a git checkout of a GitHub repository links to it on
+// GitHub at the checked out commit, and any other directory links to
+// itself on disk
+type sourceLinks struct {
+	template string
+	outDir   string
+	repos    map[string]*repo // by the directory asked about
+}
+
+// repo is a git checkout
+type repo struct {
+	top    string // its top level directory
+	url    string // on GitHub, e.g. https://github.com/hajimehoshi/ebiten
+	commit string
+}
+
+// link returns the source link for game, whose example is in dir, or ""
+// if there is none
+func (s *sourceLinks) link(game, dir string) string {
+	if s.template != "" {
+		return strings.ReplaceAll(s.template, "{game}", game)
+	}
+	if dir == "" {
+		return ""
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil || !fileExists(abs) {
+		return ""
+	}
+	if r := s.repo(filepath.Dir(abs)); r != nil {
+		if rel, err := filepath.Rel(r.top, abs); err == nil && !strings.HasPrefix(rel, "..") {
+			return r.url + "/tree/" + r.commit + "/" + filepath.ToSlash(rel)
+		}
+	}
+	return relLink(s.outDir, abs)
+}
+
+// repo returns the GitHub checkout dir is in, or nil
+// Every example is in the same one, so it's looked up once per parent
+func (s *sourceLinks) repo(dir string) *repo {
+	if r, ok := s.repos[dir]; ok {
+		return r
+	}
+	s.repos[dir] = nil
+	git := func(args ...string) string {
+		out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).Output()
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(out))
+	}
+	top := git("rev-parse", "--show-toplevel")
+	url := githubURL(git("remote", "get-url", "origin"))
+	commit := git("rev-parse", "HEAD")
+	if top == "" || url == "" || commit == "" {
+		return nil
+	}
+	r := &repo{top: filepath.FromSlash(top), url: url, commit: commit}
+	s.repos[dir] = r
+	return r
+}
+
+// githubURL returns the web address of a GitHub remote, given as HTTPS
+// or SSH, or "" for anything else
+func githubURL(remote string) string {
+	remote = strings.TrimSuffix(remote, ".git")
+	for _, prefix := range []string{"https://github.com/", "git@github.com:", "ssh://git@github.com/"} {
+		if path, ok := strings.CutPrefix(remote, prefix); ok && path != "" {
+			return "https://github.com/" + path
+		}
+	}
+	return ""
+}
+
+// relLink returns a link from the page in outDir to path
+func relLink(outDir, path string) string {
+	absOut, err := filepath.Abs(outDir)
+	if err != nil {
+		return ""
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	rel, err := filepath.Rel(absOut, absPath)
+	if err != nil {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
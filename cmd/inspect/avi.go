@@ -0,0 +1,349 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"main/pkg/avireader"
+	"main/pkg/riff"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AVI main header flags
+const avifHasIndex = 0x10
+
+// aviIfKeyframe is the idx1 keyframe flag
+const aviIfKeyframe = 0x10
+
+// aviInfo is the AVI-specific part of a report
+type aviInfo struct {
+	HasIndex    bool           `json:"has_index"` // the avih flag
+	TotalFrames int            `json:"total_frames"`
+	Streams     []aviStream    `json:"streams"`
+	Chunks      map[string]int `json:"chunks"` // movi chunks by ID
+	Index       *aviIndex      `json:"index,omitempty"`
+}
+
+// aviStream is a strl list: its strh and strf
+type aviStream struct {
+	Type    string  `json:"type"` // vids or auds
+	Handler string  `json:"handler,omitempty"`
+	Rate    float64 `json:"rate"` // frames or blocks per second
+	Length  int     `json:"length"`
+
+	Compression string `json:"compression,omitempty"` // video
+	SampleRate  int    `json:"sample_rate,omitempty"` // audio
+	Channels    int    `json:"channels,omitempty"`
+	Bits        int    `json:"bits,omitempty"`
+}
+
+// aviIndex is what the idx1 chunk says and whether it's right
+type aviIndex struct {
+	Entries   int  `json:"entries"`
+	Keyframes int  `json:"keyframes"`
+	Absolute  bool `json:"absolute"` // offsets from the file start instead of movi
+	Bad       int  `json:"bad"`      // entries not pointing at a matching chunk
+}
+
+// inspectAVI reads the avih, strh and strf headers, counts the movi
+// chunks and checks every idx1 entry against them
+func inspectAVI(r *report, f *os.File) error {
+	rf, err := riff.Open(f)
+	if err != nil {
+		return err
+	}
+	if rf.Size != r.Size {
+		r.problem("RIFF size %d doesn't match file size %d (not finalized?)", rf.Size, r.Size)
+	}
+	chunks, err := rf.Chunks(r.Size)
+	if err != nil {
+		r.problem("%v", err)
+	}
+	info := &aviInfo{Chunks: map[string]int{}}
+	r.AVI = info
+
+	hdrl, ok := riff.Find(chunks, "hdrl")
+	if !ok {
+		return errors.New("no hdrl list")
+	}
+	if err := readAVIHeaders(r, rf, hdrl); err != nil {
+		return err
+	}
+
+	movi, ok := riff.Find(chunks, "movi")
+	if !ok {
+		return errors.New("no movi list")
+	}
+	if movi.Size < 4 || movi.Offset+int64(movi.Size) > r.Size {
+		// Unfinalized or cut short: count what's there
+		movi.Size = uint32(r.Size - movi.Offset)
+	}
+	video := ""
+	for i, s := range info.Streams {
+		if s.Type == "vids" {
+			video = fmt.Sprintf("%02d", i)
+			if s.Rate > 0 {
+				r.FPS = s.Rate
+			}
+			break
+		}
+	}
+	notJPEG := 0
+	var last riff.Chunk
+	var count func(c riff.Chunk) error
+	count = func(c riff.Chunk) error {
+		if c.ID == "LIST" {
+			return rf.Each(c, count) // rec lists group a frame's chunks
+		}
+		last = c
+		info.Chunks[c.ID]++
+		if strings.HasPrefix(c.ID, video) && isVideoChunk(c.ID) && c.Size > 0 {
+			soi, err := rf.Data(riff.Chunk{Size: min(c.Size, 2), Offset: c.Offset})
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(soi, []byte{0xff, 0xd8}) {
+				notJPEG++
+			}
+		}
+		return nil
+	}
+	if err := rf.Each(movi, count); err != nil {
+		r.problem("movi: %v", err)
+		// The last chunk seen is the one that was cut, and like
+		// avireader, a frame cut short isn't counted
+		if errors.Is(err, riff.ErrTruncated) && strings.HasPrefix(last.ID, video) && isVideoChunk(last.ID) {
+			info.Chunks[last.ID]--
+		}
+	}
+	for id, n := range info.Chunks {
+		if strings.HasPrefix(id, video) && isVideoChunk(id) {
+			r.Frames += n
+		}
+	}
+	if notJPEG > 0 {
+		r.problem("%d frame(s) don't start with a JPEG marker", notJPEG)
+	}
+	if info.TotalFrames != r.Frames {
+		r.problem("avih says %d frames, movi has %d", info.TotalFrames, r.Frames)
+	}
+	for i, s := range info.Streams {
+		if s.Type == "vids" && s.Length != r.Frames {
+			r.problem("stream %d header says %d frames, movi has %d", i, s.Length, r.Frames)
+		}
+	}
+	if r.FPS > 0 {
+		r.Duration = time.Duration(float64(r.Frames) / r.FPS * float64(time.Second))
+	}
+
+	idx1, ok := riff.Find(chunks, "idx1")
+	if !ok {
+		if info.HasIndex {
+			r.problem("avih has the index flag but there is no idx1 chunk")
+		}
+		return nil
+	}
+	return checkAVIIndex(r, rf, idx1, movi)
+}
+
+// decodeAVI decodes every frame's JPEG
+func decodeAVI(r *report, f *os.File) {
+	ar, err := avireader.New(f, r.Size)
+	if err != nil {
+		r.problem("%v", err)
+		return
+	}
+	bad := 0
+	var first error
+	for {
+		_, _, err := ar.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// Skip the frame and carry on: one bad JPEG is worth knowing
+			// about, not a reason to stop
+			if bad == 0 {
+				first = err
+			}
+			bad++
+			ar.Skip()
+		}
+	}
+	if bad > 0 {
+		r.problem("%d frame(s) don't decode (first: %v)", bad, first)
+	}
+}
+
+// readAVIHeaders reads avih and the strl lists from hdrl
+func readAVIHeaders(r *report, rf *riff.File, hdrl riff.Chunk) error {
+	info := r.AVI
+	chunks, err := rf.List(hdrl)
+	if err != nil {
+		return fmt.Errorf("hdrl: %w", err)
+	}
+	avih, ok := riff.Find(chunks, "avih")
+	if !ok {
+		return errors.New("no avih header")
+	}
+	data, err := rf.Data(avih)
+	if err != nil {
+		return err
+	}
+	if len(data) < 40 {
+		return errors.New("avih header too short")
+	}
+	le := binary.LittleEndian
+	if us := le.Uint32(data[0:]); us > 0 {
+		r.FPS = 1e6 / float64(us)
+	}
+	info.HasIndex = le.Uint32(data[12:])&avifHasIndex != 0
+	info.TotalFrames = int(le.Uint32(data[16:]))
+	r.Width, r.Height = int(le.Uint32(data[32:])), int(le.Uint32(data[36:]))
+
+	for _, c := range chunks {
+		if c.ID != "LIST" || c.Kind != "strl" {
+			continue
+		}
+		strl, err := rf.List(c)
+		if err != nil {
+			return fmt.Errorf("strl: %w", err)
+		}
+		strh, ok := riff.Find(strl, "strh")
+		if !ok {
+			r.problem("stream %d has no strh header", len(info.Streams))
+			continue
+		}
+		h, err := rf.Data(strh)
+		if err != nil {
+			return err
+		}
+		if len(h) < 36 {
+			return errors.New("strh header too short")
+		}
+		s := aviStream{
+			Type:    string(h[0:4]),
+			Handler: strings.TrimRight(string(h[4:8]), "\x00"),
+			Length:  int(le.Uint32(h[32:])),
+		}
+		if scale := le.Uint32(h[20:]); scale > 0 {
+			s.Rate = float64(le.Uint32(h[24:])) / float64(scale)
+		}
+		if strf, ok := riff.Find(strl, "strf"); ok {
+			if b, err := rf.Data(strf); err == nil {
+				switch {
+				case s.Type == "vids" && len(b) >= 20:
+					s.Compression = strings.TrimRight(string(b[16:20]), "\x00")
+				case s.Type == "auds" && len(b) >= 16:
+					s.Channels = int(le.Uint16(b[2:]))
+					s.SampleRate = int(le.Uint32(b[4:]))
+					s.Bits = int(le.Uint16(b[14:]))
+				}
+			}
+		}
+		info.Streams = append(info.Streams, s)
+	}
+	if len(info.Streams) == 0 {
+		r.problem("no stream headers")
+	}
+	return nil
+}
+
+// checkAVIIndex checks that every idx1 entry points at a chunk with its
+// ID and size, and that the index covers every movi chunk
+func checkAVIIndex(r *report, rf *riff.File, idx1, movi riff.Chunk) error {
+	data, err := rf.Data(idx1)
+	if err != nil {
+		return fmt.Errorf("idx1: %w", err)
+	}
+	if len(data)%16 != 0 {
+		r.problem("idx1 size %d isn't a multiple of 16", len(data))
+	}
+	index := &aviIndex{Entries: len(data) / 16}
+	r.AVI.Index = index
+	if index.Entries == 0 {
+		r.problem("idx1 is empty")
+		return nil
+	}
+
+	// Offsets are relative to the movi list type by convention, but some
+	// writers use file offsets; the first entry tells which
+	le := binary.LittleEndian
+	base := movi.Offset
+	if int64(le.Uint32(data[8:])) >= movi.Offset {
+		base, index.Absolute = 0, true
+	}
+	var first string
+	for i := 0; i < index.Entries; i++ {
+		e := data[i*16 : i*16+16]
+		id, flags := string(e[0:4]), le.Uint32(e[4:])
+		offset, size := int64(le.Uint32(e[8:])), le.Uint32(e[12:])
+		if flags&aviIfKeyframe != 0 {
+			index.Keyframes++
+		}
+		c, err := rf.ChunkAt(base + offset)
+		if err == nil && c.ID == id && c.Size == size && c.Offset+int64(size) <= movi.Offset+int64(movi.Size) {
+			continue
+		}
+		if index.Bad == 0 {
+			first = fmt.Sprintf("#%d %s at %d", i, id, offset)
+		}
+		index.Bad++
+	}
+	if index.Bad > 0 {
+		r.problem("idx1: %d of %d entries don't point at a matching chunk (first: %s)", index.Bad, index.Entries, first)
+	}
+	indexed := 0
+	for id, n := range r.AVI.Chunks {
+		if id != "JUNK" {
+			indexed += n
+		}
+	}
+	if index.Entries != indexed {
+		r.problem("idx1 has %d entries for %d movi chunks", index.Entries, indexed)
+	}
+	return nil
+}
+
+// isVideoChunk reports whether id is a compressed or uncompressed video
+// frame, e.g. "00dc"
+func isVideoChunk(id string) bool {
+	return strings.HasSuffix(id, "dc") || strings.HasSuffix(id, "db")
+}
+
+func (info *aviInfo) print(verbose bool) {
+	for i, s := range info.Streams {
+		switch s.Type {
+		case "vids":
+			fmt.Printf("    Stream %d: video %s, %.2f fps, %d frames\n", i, s.Compression, s.Rate, s.Length)
+		case "auds":
+			fmt.Printf("    Stream %d: audio %d Hz, %d channel(s), %d-bit\n", i, s.SampleRate, s.Channels, s.Bits)
+		default:
+			fmt.Printf("    Stream %d: %s\n", i, s.Type)
+		}
+	}
+	ids := make([]string, 0, len(info.Chunks))
+	for id := range info.Chunks {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprintf("%s ×%d", id, info.Chunks[id])
+	}
+	fmt.Printf("    Chunks: %s\n", strings.Join(parts, ", "))
+	if ix := info.Index; ix != nil {
+		fmt.Printf("    Index: %d entries, %d keyframes, %d bad", ix.Entries, ix.Keyframes, ix.Bad)
+		if verbose && ix.Absolute {
+			fmt.Print(" (absolute offsets)")
+		}
+		fmt.Println()
+	} else {
+		fmt.Println("    Index: none")
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"image/gif"
+	"os"
+	"time"
+)
+
+// gifInfo is the GIF-specific part of a report
+type gifInfo struct {
+	GlobalPalette int        `json:"global_palette"` // colors, 0 if none
+	Frames        []gifFrame `json:"frames,omitempty"`
+}
+
+// gifFrame is one image of a GIF
+type gifFrame struct {
+	X       int `json:"x"`
+	Y       int `json:"y"`
+	Width   int `json:"width"`
+	Height  int `json:"height"`
+	Delay   int `json:"delay_cs"` // hundredths of a second
+	Palette int `json:"palette"`  // colors in the frame's palette
+	Dispose int `json:"dispose"`  // 0 unspecified, 1 none, 2 background, 3 previous
+}
+
+// inspectGIF decodes every frame and reports their delays and palettes
+func inspectGIF(r *report, f *os.File) error {
+	if _, err := f.Seek(0, 0); err != nil {
+		return err
+	}
+	g, err := gif.DecodeAll(f)
+	if err != nil {
+		return err
+	}
+	info := &gifInfo{}
+	r.GIF = info
+	r.Width, r.Height = g.Config.Width, g.Config.Height
+	r.Frames = len(g.Image)
+	r.LoopCount = &g.LoopCount
+	if p, ok := g.Config.ColorModel.(color.Palette); ok {
+		info.GlobalPalette = len(p)
+	}
+
+	var total time.Duration
+	for i, img := range g.Image {
+		b := img.Bounds()
+		frame := gifFrame{X: b.Min.X, Y: b.Min.Y, Width: b.Dx(), Height: b.Dy(), Palette: len(img.Palette)}
+		if i < len(g.Delay) {
+			frame.Delay = g.Delay[i]
+		}
+		if i < len(g.Disposal) {
+			frame.Dispose = int(g.Disposal[i])
+		}
+		if b.Max.X > r.Width || b.Max.Y > r.Height {
+			r.problem("frame %d (%dx%d at %d,%d) is outside the %dx%d screen",
+				i, b.Dx(), b.Dy(), b.Min.X, b.Min.Y, r.Width, r.Height)
+		}
+		total += time.Duration(frame.Delay) * 10 * time.Millisecond
+		info.Frames = append(info.Frames, frame)
+	}
+	r.Duration = total
+	if r.Frames == 0 {
+		r.problem("no frames")
+	} else if total > 0 {
+		r.FPS = float64(r.Frames) / total.Seconds()
+	}
+	return nil
+}
+
+func (info *gifInfo) print(verbose bool) {
+	if info.GlobalPalette > 0 {
+		fmt.Printf("    Global palette: %d colors\n", info.GlobalPalette)
+	}
+	if len(info.Frames) == 0 {
+		return
+	}
+	delays := make([]int, len(info.Frames))
+	palettes := make([]int, len(info.Frames))
+	for i, fr := range info.Frames {
+		delays[i], palettes[i] = fr.Delay, fr.Palette
+	}
+	fmt.Printf("    Delays: %s\n", histogram(delays, func(cs int) string { return fmt.Sprintf("%dms", cs*10) }))
+	fmt.Printf("    Palettes: %s\n", histogram(palettes, func(n int) string { return fmt.Sprintf("%d colors", n) }))
+	if verbose {
+		for i, fr := range info.Frames {
+			fmt.Printf("    #%-4d %dx%d at %d,%d  %dms  %d colors  dispose=%d\n",
+				i, fr.Width, fr.Height, fr.X, fr.Y, fr.Delay*10, fr.Palette, fr.Dispose)
+		}
+	}
+}
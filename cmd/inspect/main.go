@@ -0,0 +1,215 @@
+// inspect reports what's inside a recording: AVI, GIF or WebP
+//
+//	inspect [-json] [-v] [-decode] <file>...
+//
+// It parses the files itself rather than relying on an image decoder,
+// so it can tell a truncated AVI or a broken index from a good one and
+// sees every frame of an animated WebP. The exit code is 1 if any file
+// is unreadable or has problems
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	asJSON := flag.Bool("json", false, "print the reports as a JSON array")
+	verbose := flag.Bool("v", false, "list every GIF and WebP frame")
+	decode := flag.Bool("decode", false, "also decode every AVI and WebP frame (GIF frames are always decoded)")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: inspect [flags] <file>...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		return 2
+	}
+
+	code := 0
+	reports := make([]*report, 0, flag.NArg())
+	for _, path := range flag.Args() {
+		r := inspect(path, *decode)
+		if len(r.Problems) > 0 || r.Error != "" {
+			code = 1
+		}
+		reports = append(reports, r)
+	}
+
+	if *asJSON {
+		data, err := json.MarshalIndent(reports, "", "  ")
+		if err != nil {
+			fmt.Printf("ERROR: %v\n", err)
+			return 1
+		}
+		fmt.Println(string(data))
+		return code
+	}
+	for i, r := range reports {
+		if i > 0 {
+			fmt.Println()
+		}
+		r.print(*verbose)
+	}
+	return code
+}
+
+// report is what inspect found out about one file
+// Durations are serialized as nanoseconds, like the stats sidecars
+type report struct {
+	File     string        `json:"file"`
+	Format   string        `json:"format,omitempty"`
+	Size     int64         `json:"size"`
+	Width    int           `json:"width,omitempty"`
+	Height   int           `json:"height,omitempty"`
+	Frames   int           `json:"frames"`
+	FPS      float64       `json:"fps,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+
+	// LoopCount is the GIF or WebP loop count: 0 loops forever, -1 (GIF
+	// only) plays once
+	LoopCount *int `json:"loop_count,omitempty"`
+
+	AVI  *aviInfo  `json:"avi,omitempty"`
+	WebP *webpInfo `json:"webp,omitempty"`
+	GIF  *gifInfo  `json:"gif,omitempty"`
+
+	// Problems are defects found in a file that could still be read
+	Problems []string `json:"problems,omitempty"`
+
+	// Error is why the file couldn't be read at all
+	Error string `json:"error,omitempty"`
+}
+
+func (r *report) problem(format string, args ...any) {
+	r.Problems = append(r.Problems, fmt.Sprintf(format, args...))
+}
+
+// inspect detects the format of the file at path by its magic bytes
+func inspect(path string, decode bool) *report {
+	r := &report{File: path}
+	f, err := os.Open(path)
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	r.Size = stat.Size()
+
+	var magic [12]byte
+	if _, err := io.ReadFull(f, magic[:]); err != nil {
+		r.Error = "too short for a recording"
+		return r
+	}
+	switch {
+	case bytes.HasPrefix(magic[:], []byte("GIF8")):
+		r.Format = "gif"
+		err = inspectGIF(r, f)
+	case string(magic[0:4]) == "RIFF" && string(magic[8:12]) == "AVI ":
+		r.Format = "avi"
+		err = inspectAVI(r, f)
+		if err == nil && decode {
+			decodeAVI(r, f)
+		}
+	case string(magic[0:4]) == "RIFF" && string(magic[8:12]) == "WEBP":
+		r.Format = "webp"
+		err = inspectWebP(r, f)
+		if err == nil && decode {
+			decodeWebP(r, f)
+		}
+	default:
+		err = errors.New("not an AVI, GIF or WebP file")
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// print writes r for people
+func (r *report) print(verbose bool) {
+	fmt.Printf("==> %s\n", r.File)
+	if r.Format != "" {
+		fmt.Printf("    Format: %s, %.2f MB\n", strings.ToUpper(r.Format), float64(r.Size)/(1<<20))
+	}
+	if r.Error != "" {
+		fmt.Printf("ERROR: %s\n", r.Error)
+		for _, p := range r.Problems {
+			fmt.Printf("    - %s\n", p)
+		}
+		return
+	}
+	fmt.Printf("    Size: %dx%d\n", r.Width, r.Height)
+	fmt.Printf("    Frames: %d", r.Frames)
+	if r.FPS > 0 {
+		fmt.Printf(" at %.2f fps", r.FPS)
+	}
+	fmt.Printf(", %s\n", r.Duration.Round(time.Millisecond))
+	if r.LoopCount != nil {
+		switch *r.LoopCount {
+		case -1:
+			fmt.Println("    Loop: plays once")
+		case 0:
+			fmt.Println("    Loop: forever")
+		default:
+			fmt.Printf("    Loop: %d times\n", *r.LoopCount)
+		}
+	}
+	switch {
+	case r.AVI != nil:
+		r.AVI.print(verbose)
+	case r.WebP != nil:
+		r.WebP.print(verbose)
+	case r.GIF != nil:
+		r.GIF.print(verbose)
+	}
+	if len(r.Problems) == 0 {
+		fmt.Println("✓ No problems found")
+		return
+	}
+	fmt.Printf("✗ %d problem(s):\n", len(r.Problems))
+	for _, p := range r.Problems {
+		fmt.Printf("    - %s\n", p)
+	}
+}
+
+// histogram formats how often each value occurs, most common first,
+// e.g. "33ms ×299, 34ms ×1"
+func histogram[T comparable](values []T, format func(T) string) string {
+	counts := map[T]int{}
+	var order []T
+	for _, v := range values {
+		if counts[v] == 0 {
+			order = append(order, v)
+		}
+		counts[v]++
+	}
+	sort.SliceStable(order, func(i, j int) bool { return counts[order[i]] > counts[order[j]] })
+	parts := make([]string, 0, len(order))
+	for i, v := range order {
+		if i == 5 {
+			parts = append(parts, fmt.Sprintf("… %d more", len(order)-i))
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%s ×%d", format(v), counts[v]))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"main/pkg/riff"
+	"main/pkg/webpanim"
+	"os"
+	"time"
+
+	"golang.org/x/image/webp"
+)
+
+// VP8X feature flags
+const (
+	webpFlagAnimation = 0x02
+	webpFlagAlpha     = 0x10
+)
+
+// webpInfo is the WebP-specific part of a report
+type webpInfo struct {
+	Animated   bool        `json:"animated"`
+	Alpha      bool        `json:"alpha"`
+	Background string      `json:"background,omitempty"` // ANIM background color as #rrggbbaa
+	Frames     []webpFrame `json:"frames,omitempty"`
+}
+
+// webpFrame is one ANMF chunk
+type webpFrame struct {
+	X        int    `json:"x"`
+	Y        int    `json:"y"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	Duration int    `json:"duration_ms"`
+	Blend    bool   `json:"blend"`   // alpha-blend onto the canvas, else replace
+	Dispose  bool   `json:"dispose"` // clear to the background after showing
+	Codec    string `json:"codec"`   // VP8L, VP8 or VP8+ALPH
+}
+
+// inspectWebP walks the VP8X, ANIM and ANMF chunks
+func inspectWebP(r *report, f *os.File) error {
+	rf, err := riff.Open(f)
+	if err != nil {
+		return err
+	}
+	if rf.Size != r.Size {
+		r.problem("RIFF size %d doesn't match file size %d", rf.Size, r.Size)
+	}
+	chunks, err := rf.Chunks(r.Size)
+	if err != nil {
+		r.problem("%v", err)
+	}
+	info := &webpInfo{}
+	r.WebP = info
+
+	vp8x, ok := riff.Find(chunks, "VP8X")
+	if !ok {
+		// A simple file: one VP8 or VP8L image
+		if _, err := f.Seek(0, 0); err != nil {
+			return err
+		}
+		cfg, err := webp.DecodeConfig(f)
+		if err != nil {
+			return err
+		}
+		r.Width, r.Height, r.Frames = cfg.Width, cfg.Height, 1
+		_, info.Alpha = riff.Find(chunks, "VP8L") // VP8L always carries alpha
+		return nil
+	}
+	hdr, err := rf.Data(vp8x)
+	if err != nil {
+		return err
+	}
+	if len(hdr) < 10 {
+		return errors.New("VP8X chunk too short")
+	}
+	info.Animated = hdr[0]&webpFlagAnimation != 0
+	info.Alpha = hdr[0]&webpFlagAlpha != 0
+	r.Width, r.Height = u24(hdr[4:])+1, u24(hdr[7:])+1
+
+	if !info.Animated {
+		if _, ok := riff.Find(chunks, "VP8 "); !ok {
+			if _, ok := riff.Find(chunks, "VP8L"); !ok {
+				r.problem("no image data")
+			}
+		}
+		r.Frames = 1
+		return nil
+	}
+
+	anim, ok := riff.Find(chunks, "ANIM")
+	if !ok {
+		r.problem("animated but no ANIM chunk")
+	} else if data, err := rf.Data(anim); err != nil || len(data) < 6 {
+		r.problem("ANIM chunk too short")
+	} else {
+		loops := int(binary.LittleEndian.Uint16(data[4:6]))
+		r.LoopCount = &loops
+		info.Background = fmt.Sprintf("#%02x%02x%02x%02x", data[2], data[1], data[0], data[3])
+	}
+
+	var total time.Duration
+	for _, c := range chunks {
+		if c.ID != "ANMF" {
+			continue
+		}
+		frame, err := webpFrameOf(rf, c)
+		if err != nil {
+			r.problem("frame %d: %v", len(info.Frames), err)
+			continue
+		}
+		if frame.X+frame.Width > r.Width || frame.Y+frame.Height > r.Height {
+			r.problem("frame %d (%dx%d at %d,%d) is outside the %dx%d canvas",
+				len(info.Frames), frame.Width, frame.Height, frame.X, frame.Y, r.Width, r.Height)
+		}
+		total += time.Duration(frame.Duration) * time.Millisecond
+		info.Frames = append(info.Frames, frame)
+	}
+	r.Frames = len(info.Frames)
+	r.Duration = total
+	if r.Frames == 0 {
+		r.problem("animated but no frames")
+	} else if total > 0 {
+		r.FPS = float64(r.Frames) / total.Seconds()
+	}
+	return nil
+}
+
+// decodeWebP composites every frame, as a player would
+func decodeWebP(r *report, f *os.File) {
+	d, err := webpanim.NewDecoder(f, r.Size)
+	if err != nil {
+		r.problem("%v", err)
+		return
+	}
+	for {
+		if _, _, err := d.Next(); err == io.EOF {
+			return
+		} else if err != nil {
+			r.problem("%v", err)
+			return
+		}
+	}
+}
+
+// webpFrameOf parses an ANMF chunk's header and frame data chunks
+func webpFrameOf(rf *riff.File, c riff.Chunk) (webpFrame, error) {
+	if c.Size < 16 {
+		return webpFrame{}, errors.New("ANMF chunk too short")
+	}
+	data, err := rf.Data(riff.Chunk{ID: c.ID, Size: 16, Offset: c.Offset})
+	if err != nil {
+		return webpFrame{}, err
+	}
+	frame := webpFrame{
+		X:        u24(data[0:]) * 2,
+		Y:        u24(data[3:]) * 2,
+		Width:    u24(data[6:]) + 1,
+		Height:   u24(data[9:]) + 1,
+		Duration: u24(data[12:]),
+		Blend:    data[15]&0x02 == 0,
+		Dispose:  data[15]&0x01 != 0,
+	}
+
+	// The frame data is ALPH and VP8, or VP8L, as chunks of their own
+	chunks, err := rf.Within(c, 16)
+	if err != nil {
+		return frame, err
+	}
+	_, alpha := riff.Find(chunks, "ALPH")
+	switch _, vp8l := riff.Find(chunks, "VP8L"); {
+	case vp8l:
+		frame.Codec = "VP8L"
+	case alpha:
+		frame.Codec = "VP8+ALPH"
+	default:
+		frame.Codec = "VP8"
+		if _, ok := riff.Find(chunks, "VP8 "); !ok {
+			return frame, errors.New("no image data")
+		}
+	}
+	return frame, nil
+}
+
+func u24(b []byte) int {
+	return int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+}
+
+func (info *webpInfo) print(verbose bool) {
+	fmt.Printf("    Animated: %t, alpha: %t\n", info.Animated, info.Alpha)
+	if info.Background != "" {
+		fmt.Printf("    Background: %s\n", info.Background)
+	}
+	if len(info.Frames) == 0 {
+		return
+	}
+	durations := make([]int, len(info.Frames))
+	codecs := make([]string, len(info.Frames))
+	for i, fr := range info.Frames {
+		durations[i], codecs[i] = fr.Duration, fr.Codec
+	}
+	fmt.Printf("    Durations: %s\n", histogram(durations, func(ms int) string { return fmt.Sprintf("%dms", ms) }))
+	fmt.Printf("    Codecs: %s\n", histogram(codecs, func(s string) string { return s }))
+	if verbose {
+		for i, fr := range info.Frames {
+			fmt.Printf("    #%-4d %dx%d at %d,%d  %dms  %s  blend=%t dispose=%t\n",
+				i, fr.Width, fr.Height, fr.X, fr.Y, fr.Duration, fr.Codec, fr.Blend, fr.Dispose)
+		}
+	}
+}
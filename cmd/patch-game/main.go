@@ -0,0 +1,128 @@
+// patch-game rewrites an ebiten game's source so it records itself
+//
+// Every ebiten.RunGame(game) and ebiten.RunGameWithOptions(game, opts)
+// call becomes a call on recorder.WrapGame(game, ...), enclosed in
+// recorder.CloseAll, and the recorder and time imports are added where
+// needed; see pkg/gamepatch
+//
+// With -overlay the game's files are left alone: the patched copies go
+// to a work directory, with an overlay file and go.work to build them
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"main/pkg/gamepatch"
+	"os"
+	"strings"
+	"time"
+)
+
+func main() {
+	output := flag.String("output", "recording.avi", "recording output path passed to WrapGame")
+	duration := flag.Duration("duration", 10*time.Second, "auto-record duration (0 = manual)")
+	quality := flag.Int("quality", 85, "JPEG quality (1-100)")
+	recorderPath := flag.String("import", "", "import path of the recorder package (default: <root module>/pkg/recorder)")
+	root := flag.String("root", ".", "directory of the module providing the recorder")
+	overlay := flag.String("overlay", "", "write patched copies, overlay.json and go.work to this directory instead of patching in place")
+	dryRun := flag.Bool("n", false, "print the patched files instead of writing them")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: patch-game [flags] <dir | file.go>...")
+		fmt.Fprintln(os.Stderr, "       patch-game -overlay <work dir> [flags] <game dir>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	opts := gamepatch.Options{
+		Output:       *output,
+		Quality:      *quality,
+		Duration:     *duration,
+		RecorderPath: *recorderPath,
+	}
+	if opts.RecorderPath == "" && *overlay == "" {
+		module, err := gamepatch.ModulePath(*root)
+		if err != nil {
+			fmt.Printf("ERROR: %v\n", err)
+			os.Exit(1)
+		}
+		opts.RecorderPath = module + "/pkg/recorder"
+	}
+	p := gamepatch.New(opts)
+
+	var total int
+	var err error
+	if *overlay != "" {
+		total, err = injectGame(p, flag.Arg(0), *overlay, *root)
+	} else {
+		total, err = patchFiles(p, flag.Args(), *dryRun)
+	}
+	for _, msg := range p.Wrapped {
+		fmt.Println(msg)
+	}
+	for _, msg := range p.Skipped {
+		fmt.Println(msg)
+	}
+	if err != nil && !errors.Is(err, gamepatch.ErrNothingPatched) {
+		fmt.Printf("ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	if total == 0 {
+		if len(p.Skipped) == 0 {
+			fmt.Printf("ERROR: nothing patched: no ebiten.RunGame or ebiten.RunGameWithOptions call in %s\n",
+				strings.Join(flag.Args(), ", "))
+		} else {
+			fmt.Println("ERROR: nothing patched: every RunGame call was skipped")
+		}
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Patched %d call(s)\n", total)
+}
+
+// patchFiles patches files in place, or prints them with dryRun
+func patchFiles(p *gamepatch.Patcher, args []string, dryRun bool) (int, error) {
+	files, err := gamepatch.GoFiles(args)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, path := range files {
+		src, n, err := p.PatchFile(path)
+		if err != nil {
+			return total, err
+		}
+		if n == 0 {
+			continue
+		}
+		total += n
+		if dryRun {
+			fmt.Printf("==> %s\n%s", path, src)
+			continue
+		}
+		if err := os.WriteFile(path, src, 0644); err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// injectGame writes the overlay for the game in dir and prints how to
+// build with it
+func injectGame(p *gamepatch.Patcher, dir, workDir, root string) (int, error) {
+	in, err := p.Inject(dir, workDir, root)
+	if err != nil {
+		return 0, err
+	}
+	fmt.Printf("==> Overlay: %s\n", in.Overlay)
+	if in.Work != "" {
+		fmt.Printf("    Workspace: %s\n", in.Work)
+		fmt.Printf("    Run: cd %s && GOWORK=%s go run -overlay %s .\n", in.Dir, in.Work, in.Overlay)
+	} else {
+		fmt.Printf("    Run: cd %s && go run -overlay %s .\n", in.Dir, in.Overlay)
+	}
+	return len(p.Wrapped), nil
+}
@@ -0,0 +1,276 @@
+// record builds an ebiten game so it records itself, runs it and checks
+// the recording
+//
+//	record [flags] <game dir> [game args...]
+//
+// The game's sources are left alone: pkg/gamepatch builds it through a
+// go build overlay in its own module, and a loose directory without a
+// go.mod gets this module's ebiten version. The exit code is 0 if a valid
+// recording was written, the game's own exit code if it failed, and 1 for
+// any other failure
+//
+// Settings not given as flags come from the game's profile in
+// scripts/profiles.json (see pkg/profile); a game the profile skips
+// exits with 0 without recording
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"main/pkg/gamepatch"
+	"main/pkg/profile"
+	"main/pkg/project"
+	"main/pkg/settings"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() (code int) {
+	duration := flag.Duration("duration", 10*time.Second, "how long to record (0 = until the game exits)")
+	format := flag.String("format", "", "avi, gif or webp (default: from -output, else avi)")
+	output := flag.String("output", "", "recording path (default: recordings/<game>.<format> in the project)")
+	quality := flag.Int("quality", 85, "JPEG quality for AVI (1-100)")
+	fps := flag.Int("fps", 0, "recording frame rate (default: 30)")
+	window := flag.String("window", "", "window size as WxH, 720p or 1080p (default: the game's)")
+	profilesPath := flag.String("profiles", "", "profile file (default: "+profile.DefaultPath+" in the project, if any)")
+	root := flag.String("root", "", "project directory providing pkg/recorder (default: found from the working directory)")
+	script := flag.String("script", "", "input script (default: scripts/bots/<game>.json in the project, if any)")
+	timeout := flag.Duration("timeout", 2*time.Minute, "extra time allowed for the game to start and save, on top of -duration")
+	keep := flag.Bool("keep", false, "keep the work directory with the patched sources")
+	resultPath := flag.String("result", "", "write the outcome as JSON to this file, for batch runs")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: record [flags] <game dir> [game args...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		return 2
+	}
+	set := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	dir, gameArgs := flag.Arg(0), flag.Args()[1:]
+	name := filepath.Base(filepath.Clean(dir))
+
+	res := result{Game: name}
+	fail := func(code int, format string, args ...any) int {
+		res.Error = fmt.Sprintf(format, args...)
+		fmt.Printf("ERROR: %s\n", res.Error)
+		return code
+	}
+	if *resultPath != "" {
+		defer func() {
+			res.ExitCode = code
+			if err := res.write(*resultPath); err != nil {
+				fmt.Printf("ERROR: %v\n", err)
+			}
+		}()
+	}
+
+	if *root == "" {
+		var err error
+		if *root, err = project.FindRoot("."); err != nil {
+			return fail(2, "%v or pass -root", err)
+		}
+	}
+
+	// Flags win over the profile
+	if *profilesPath == "" {
+		if path := filepath.Join(*root, profile.DefaultPath); fileExists(path) {
+			*profilesPath = path
+		}
+	}
+	var prof profile.Profile
+	if *profilesPath != "" {
+		ps, err := profile.Load(*profilesPath)
+		if err != nil {
+			return fail(2, "%v", err)
+		}
+		prof = ps.For(name)
+	}
+	if prof.Skip {
+		res.Skipped = prof.Reason
+		fmt.Printf("==> Skipping '%s': %s\n", name, prof.Reason)
+		return 0
+	}
+	if !set["duration"] && prof.Duration != nil {
+		*duration = time.Duration(*prof.Duration)
+	}
+	if *format == "" && filepath.Ext(*output) == "" {
+		*format = prof.Format
+	}
+	if !set["quality"] && prof.Quality != 0 {
+		*quality = prof.Quality
+	}
+	if *fps == 0 {
+		*fps = prof.FPS
+	}
+	if *window == "" {
+		*window = prof.Window
+	}
+	if *script == "" {
+		*script = prof.Script
+	}
+	gameArgs = append(prof.Args, gameArgs...)
+	if *fps < 0 {
+		return fail(2, "invalid -fps %d", *fps)
+	}
+	if *window != "" {
+		if _, err := settings.ParseSize(*window); err != nil {
+			return fail(2, "invalid -window: %v", err)
+		}
+	}
+
+	outPath, f, err := outputPath(*output, *format, *root, name)
+	if err != nil {
+		return fail(2, "%v", err)
+	}
+	res.Output, res.Format = outPath, f
+	if *script == "" {
+		if path := filepath.Join(*root, "scripts", "bots", name+".json"); fileExists(path) {
+			*script = path
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Printf("==> Recording '%s' for %s\n", name, *duration)
+	fmt.Printf("    Source: %s\n", dir)
+	fmt.Printf("    Output: %s (%s)\n", outPath, f)
+	if *profilesPath != "" {
+		fmt.Printf("    Profile: %s\n", *profilesPath)
+	}
+	if *script != "" {
+		fmt.Printf("    Input script: %s\n", *script)
+	}
+
+	work, err := os.MkdirTemp("", "record-"+name+"-")
+	if err != nil {
+		return fail(1, "%v", err)
+	}
+	if *keep {
+		fmt.Printf("    Work: %s\n", work)
+	} else {
+		defer os.RemoveAll(work)
+	}
+
+	// Patch and build
+	p := gamepatch.New(gamepatch.Options{Output: outPath, Quality: *quality, Duration: *duration})
+	in, err := p.Inject(dir, work, *root)
+	for _, msg := range append(p.Wrapped, p.Skipped...) {
+		fmt.Printf("    %s\n", msg)
+	}
+	if err != nil {
+		return fail(1, "could not patch '%s': %v", name, err)
+	}
+	fmt.Println("==> Building...")
+	bin := filepath.Join(work, name)
+	if runtime.GOOS == "windows" {
+		bin += ".exe"
+	}
+	build := in.Command(ctx, "build", "-o", bin, ".")
+	build.Stdout, build.Stderr = os.Stdout, os.Stderr
+	if err := build.Run(); err != nil {
+		return fail(1, "build failed: %v", err)
+	}
+
+	// A stale file from an earlier run must not pass as this run's output
+	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+		return fail(1, "%v", err)
+	}
+	os.Remove(outPath)
+
+	// Run
+	fmt.Println("==> Running game...")
+	if *duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *duration+*timeout)
+		defer cancel()
+	}
+	game := exec.CommandContext(ctx, bin, gameArgs...)
+	game.Dir = in.Dir // games load assets relative to their directory
+	game.Stdout, game.Stderr = os.Stdout, os.Stderr
+	game.Env = append(append(os.Environ(), prof.Environ()...), "RECORD_GAME="+name)
+	if *script != "" {
+		game.Env = append(game.Env, "RECORD_INPUT_SCRIPT="+*script)
+	}
+	if *fps > 0 {
+		game.Env = append(game.Env, fmt.Sprintf("RECORD_FPS=%d", *fps))
+	}
+	if *window != "" {
+		game.Env = append(game.Env, "RECORD_WINDOW_SIZE="+*window)
+	}
+	if err := game.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fail(1, "game did not exit within %s", *duration+*timeout)
+		}
+		var exit *exec.ExitError
+		if errors.As(err, &exit) && exit.ExitCode() > 0 {
+			return fail(exit.ExitCode(), "game exited with code %d", exit.ExitCode())
+		}
+		return fail(1, "game failed: %v", err)
+	}
+
+	// Verify
+	info, err := verify(outPath, f)
+	res.Frames, res.Bytes = info.frames, info.size
+	if err != nil {
+		return fail(1, "%v", err)
+	}
+	fmt.Printf("✓ Recording saved: %s (%d frames, %.2f MB)\n", outPath, info.frames, float64(info.size)/(1<<20))
+	return 0
+}
+
+// Recording formats, named like their file extensions; WrapGame picks
+// the recorder from the output path's extension
+const (
+	formatAVI  = "avi"
+	formatGIF  = "gif"
+	formatWebP = "webp"
+)
+
+// outputPath resolves the recording path and format from the flags
+// A path without an extension gets the format's; a conflicting one is
+// an error
+func outputPath(output, format, root, name string) (string, string, error) {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(output), "."))
+	f := strings.ToLower(format)
+	if f == "" {
+		f = ext
+	}
+	switch f {
+	case formatAVI, formatGIF, formatWebP:
+	case "":
+		f = formatAVI
+	default:
+		return "", "", fmt.Errorf("unknown format %q (want avi, gif or webp)", f)
+	}
+
+	switch {
+	case output == "":
+		output = filepath.Join(root, "recordings", name+"."+f)
+	case ext == "":
+		output += "." + f
+	case ext != f:
+		return "", "", fmt.Errorf("-output %s doesn't match -format %s", output, f)
+	}
+	output, err := filepath.Abs(output)
+	return output, f, err
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
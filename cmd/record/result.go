@@ -0,0 +1,27 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// result is the outcome of a run, written with -result so cmd/batch
+// doesn't have to parse the log
+type result struct {
+	Game     string `json:"game"`
+	Output   string `json:"output,omitempty"`
+	Format   string `json:"format,omitempty"`
+	Frames   int    `json:"frames"`
+	Bytes    int64  `json:"bytes"`
+	ExitCode int    `json:"exit_code"`
+	Error    string `json:"error,omitempty"`
+	Skipped  string `json:"skipped,omitempty"` // the profile's reason for skipping the game
+}
+
+func (r result) write(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image/gif"
+	"main/pkg/avireader"
+	"main/pkg/riff"
+	"os"
+)
+
+// recordingInfo is what verify learned about a recording
+type recordingInfo struct {
+	frames int
+	size   int64
+}
+
+// verify checks that path is a complete recording in format f with at
+// least one frame
+func verify(path, f string) (recordingInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return recordingInfo{}, fmt.Errorf("no recording at %s", path)
+		}
+		return recordingInfo{}, err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return recordingInfo{}, err
+	}
+	info := recordingInfo{size: stat.Size()}
+	switch f {
+	case formatGIF:
+		g, err := gif.DecodeAll(file)
+		if err != nil {
+			return info, fmt.Errorf("%s: invalid GIF: %w", path, err)
+		}
+		info.frames = len(g.Image)
+	case formatAVI:
+		info.frames, err = countAVI(file, stat.Size())
+	case formatWebP:
+		info.frames, err = countWebP(file, stat.Size())
+	}
+	if err != nil {
+		return info, fmt.Errorf("%s: invalid %s: %w", path, f, err)
+	}
+	if info.frames == 0 {
+		return info, fmt.Errorf("%s: recording has no frames", path)
+	}
+	return info, nil
+}
+
+// countAVI counts the frames of an AVI
+// A file cut short, e.g. because the recorder never finalized it, fails
+func countAVI(file *os.File, size int64) (int, error) {
+	r, err := avireader.New(file, size)
+	if err != nil {
+		return 0, err
+	}
+	if r.Truncated {
+		return 0, errors.New("file ends early (not finalized?)")
+	}
+	return r.Len(), nil
+}
+
+// countWebP counts the frames of a WebP, 1 for a still image
+// A file cut short fails like an AVI
+func countWebP(file *os.File, size int64) (int, error) {
+	rf, err := riff.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	if rf.Form != "WEBP" {
+		return 0, errors.New("not a RIFF WEBP file")
+	}
+	if rf.Size != size {
+		return 0, fmt.Errorf("RIFF size %d doesn't match file size %d (not finalized?)", rf.Size, size)
+	}
+	chunks, err := rf.Chunks(size)
+	if err != nil {
+		return 0, err
+	}
+	frames := 0
+	for _, c := range chunks {
+		if c.ID == "ANMF" {
+			frames++
+		}
+	}
+	return max(frames, 1), nil
+}
@@ -0,0 +1,133 @@
+// thumbnail saves a representative frame of a recording as a PNG, or a
+// contact sheet of frames from across it
+//
+//	thumbnail [-at 5s] [-width 640] [-o out.png] <recording>
+//	thumbnail -sheet [-n 12] [-cols 4] [-o out.png] <recording>
+//
+// Without -at it picks the most visually varied frame: the one whose
+// colors have the highest entropy, which passes over title cards, fades
+// and mostly empty screens. It reads AVI, GIF and WebP recordings
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"main/pkg/recording"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	output := flag.String("o", "", "output PNG (default: <recording>.thumb.png, or .sheet.png with -sheet)")
+	at := flag.Duration("at", 0, "take the frame shown at this time instead of picking one")
+	width := flag.Int("width", 0, "scale to this width, keeping the aspect ratio (default: the frame's; 320 per frame with -sheet)")
+	candidates := flag.Int("candidates", 60, "frames, evenly spaced, to pick the thumbnail from")
+	sheet := flag.Bool("sheet", false, "tile frames from across the recording into a contact sheet")
+	n := flag.Int("n", 12, "frames in a contact sheet")
+	cols := flag.Int("cols", 0, "contact sheet columns (default: about square)")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: thumbnail [flags] <recording>")
+		fmt.Fprintln(os.Stderr, "Example: thumbnail -width 1280 recordings/flappy.avi")
+		fmt.Fprintln(os.Stderr, "Example: thumbnail -sheet -n 16 recordings/flappy.avi")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		return 2
+	}
+	input := flag.Arg(0)
+	atSet := false
+	flag.Visit(func(f *flag.Flag) { atSet = atSet || f.Name == "at" })
+	switch {
+	case *width < 0 || *at < 0:
+		fmt.Println("ERROR: -width and -at can't be negative")
+		return 2
+	case *n < 1 || *candidates < 1 || *cols < 0:
+		fmt.Println("ERROR: -n and -candidates must be at least 1, -cols can't be negative")
+		return 2
+	}
+	if *output == "" {
+		base := strings.TrimSuffix(input, filepath.Ext(input))
+		if *sheet {
+			*output = base + ".sheet.png"
+		} else {
+			*output = base + ".thumb.png"
+		}
+	}
+
+	src, err := recording.Open(input)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return 1
+	}
+	defer src.Close()
+
+	var img image.Image
+	if *sheet {
+		fmt.Printf("==> Contact sheet of %s\n", input)
+		tile := *width
+		if tile == 0 {
+			tile = 320
+		}
+		img, err = contactSheet(src, input, *n, *cols, tile)
+	} else {
+		fmt.Printf("==> Thumbnail of %s\n", input)
+		var t time.Duration
+		var score float64
+		if atSet {
+			img, t, err = recording.FrameAt(src, *at)
+		} else {
+			img, t, score, err = recording.Pick(src, *candidates)
+		}
+		if err == nil {
+			fmt.Printf("    Frame: at %s", clock(t))
+			if !atSet {
+				fmt.Printf(" (entropy %.2f bits)", score)
+			}
+			fmt.Println()
+			if *width > 0 {
+				img = recording.Scale(img, *width)
+			}
+		}
+	}
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return 1
+	}
+
+	if err := writePNG(*output, img); err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return 1
+	}
+	b := img.Bounds()
+	fmt.Printf("✓ Saved: %s (%dx%d)\n", *output, b.Dx(), b.Dy())
+	return 0
+}
+
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// clock formats t as m:ss.s
+func clock(t time.Duration) string {
+	m := int(t / time.Minute)
+	s := (t % time.Minute).Seconds()
+	return fmt.Sprintf("%d:%04.1f", m, s)
+}
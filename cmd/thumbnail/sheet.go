@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"main/pkg/recording"
+	"math"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Contact sheet layout, in pixels
+const (
+	sheetGap    = 8
+	sheetHeader = 32
+)
+
+var (
+	sheetBackground = color.RGBA{0x20, 0x20, 0x20, 0xff}
+	sheetText       = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	sheetLabel      = color.RGBA{0, 0, 0, 0xa0}
+)
+
+// contactSheet tiles n frames from the middles of n equal spans of the
+// recording, each tile width pixels wide and labelled with its time,
+// under a header with the file name, frame size, rate and length
+// A recording with fewer than n frames gets a tile per frame
+func contactSheet(src recording.Source, path string, n, cols, width int) (image.Image, error) {
+	total := src.Duration()
+
+	// Sources only go forward, so past the last frame there's nothing
+	// more to pick, and the grid is sized from the frames picked
+	type pick struct {
+		img image.Image
+		t   time.Duration
+	}
+	var picks []pick
+	for i := 0; i < n; i++ {
+		want := time.Duration((float64(i) + 0.5) / float64(n) * float64(total))
+		if err := src.SkipTo(want); err != nil {
+			return nil, err
+		}
+		img, t, _, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		picks = append(picks, pick{recording.Copy(img), t})
+	}
+	if len(picks) == 0 {
+		return nil, errors.New("no frames")
+	}
+
+	shown := len(picks)
+	if cols == 0 {
+		cols = int(math.Ceil(math.Sqrt(float64(shown))))
+	}
+	cols = min(cols, shown)
+	rows := (shown + cols - 1) / cols
+
+	// The first frame sets the tile size
+	b := picks[0].img.Bounds()
+	frameW, frameH := b.Dx(), b.Dy()
+	h := max(int(math.Round(float64(frameH)*float64(width)/float64(frameW))), 1)
+	tile := image.Rect(0, 0, width, h)
+	w := cols*width + (cols+1)*sheetGap
+	sheet := image.NewRGBA(image.Rect(0, 0, w, sheetHeader+rows*h+(rows+1)*sheetGap))
+	draw.Draw(sheet, sheet.Rect, image.NewUniform(sheetBackground), image.Point{}, draw.Src)
+	label := face(max(width/24, 10))
+	header := fmt.Sprintf("%s   %dx%d, %.2f fps, %s", filepath.Base(path), frameW, frameH, src.FPS(), clock(total))
+	drawText(sheet, face(16), header, sheetGap-3, sheetHeader, nil)
+
+	scaler := draw.Interpolator(draw.CatmullRom)
+	if width%frameW == 0 {
+		scaler = draw.NearestNeighbor
+	}
+	pad := max(width/80, 2)
+	for i, p := range picks {
+		x := sheetGap + (i%cols)*(width+sheetGap)
+		y := sheetHeader + sheetGap + (i/cols)*(h+sheetGap)
+		dst := tile.Add(image.Pt(x, y))
+		scaler.Scale(sheet, dst, p.img, p.img.Bounds(), draw.Src, nil)
+		drawText(sheet, label, clock(p.t), dst.Min.X+pad, dst.Max.Y-pad, image.NewUniform(sheetLabel))
+	}
+	fmt.Printf("    Frames: %d in %d column(s), %s apart\n", shown, cols, (total / time.Duration(shown)).Round(time.Millisecond))
+	return sheet, nil
+}
+
+var goRegular *opentype.Font
+
+// face returns Go Regular at size pixels
+func face(size int) font.Face {
+	if goRegular == nil {
+		var err error
+		if goRegular, err = opentype.Parse(goregular.TTF); err != nil {
+			panic(err) // goregular is embedded, so this can't fail
+		}
+	}
+	f, err := opentype.NewFace(goRegular, &opentype.FaceOptions{Size: float64(size), DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// drawText draws s in a box whose bottom left corner is at x, y, filled
+// with bg unless bg is nil
+func drawText(dst draw.Image, f font.Face, s string, x, y int, bg image.Image) {
+	const pad = 3
+	m := f.Metrics()
+	d := font.Drawer{Dst: dst, Src: image.NewUniform(sheetText), Face: f}
+	if bg != nil {
+		box := image.Rect(x, y-m.Ascent.Ceil()-m.Descent.Ceil()-2*pad, x+d.MeasureString(s).Ceil()+2*pad, y)
+		draw.Draw(dst, box, bg, image.Point{}, draw.Over)
+	}
+	d.Dot = fixed.P(x+pad, y-pad-m.Descent.Ceil())
+	d.DrawString(s)
+}
@@ -5,6 +5,7 @@ import (
 	"image"
 	"image/color"
 	"log"
+	"main/pkg/inputrec"
 	"main/pkg/recorder"
 	"os"
 	"strings"
@@ -44,7 +45,7 @@ type exampleGame struct {
 	message string
 
 	inputHandlers []*input.Handler
-	inputSystem   input.System
+	inputSystem   inputrec.System
 
 	// Recording
 	recorder           *recorder.MJPEGRecorder
@@ -63,6 +64,20 @@ func newExampleGame() *exampleGame {
 		DevicesEnabled: input.AnyDevice,
 	})
 
+	// INPUT_RECORD logs every action per tick, INPUT_REPLAY plays such a
+	// log back so the same session can be re-recorded with other settings
+	if path := os.Getenv("INPUT_REPLAY"); path != "" {
+		if err := g.inputSystem.ReplayFrom(path); err != nil {
+			log.Fatalf("Failed to load input replay: %v", err)
+		}
+	} else if path := os.Getenv("INPUT_RECORD"); path != "" {
+		if err := g.inputSystem.RecordTo(path,
+			ActionDebug, ActionMoveLeft, ActionMoveUp, ActionMoveRight,
+			ActionMoveDown, ActionExit, ActionTeleport); err != nil {
+			log.Fatalf("Failed to start input recording: %v", err)
+		}
+	}
+
 	// Check for AUTO_RECORD environment variable
 	if os.Getenv("AUTO_RECORD") != "" {
 		g.autoRecord = true
@@ -138,6 +153,7 @@ func (g *exampleGame) Update() error {
 	if g.autoRecord && g.recorder.IsRecording() {
 		elapsed := time.Since(g.autoRecordStart)
 		if elapsed >= g.autoRecordDuration {
+			g.inputSystem.Close()
 			if err := g.recorder.Stop(); err != nil {
 				log.Printf("Failed to save recording: %v", err)
 				os.Exit(1)
@@ -180,6 +196,7 @@ func (g *exampleGame) Update() error {
 		if g.recorder.IsRecording() {
 			g.recorder.Stop()
 		}
+		g.inputSystem.Close()
 		os.Exit(0)
 	}
 
@@ -4,19 +4,21 @@ go 1.25.3
 
 require (
 	github.com/HugoSmits86/nativewebp v1.2.0
+	github.com/ebitengine/purego v0.9.0
 	github.com/hajimehoshi/ebiten/v2 v2.9.3
+	github.com/icza/mjpeg v0.0.0-20230330134156-38318e5ab8f4
+	github.com/jezek/xgb v1.1.1
 	github.com/quasilyte/ebitengine-input v0.9.1
+	golang.org/x/image v0.31.0
 )
 
 require (
 	github.com/ebitengine/gomobile v0.0.0-20250923094054-ea854a63cce1 // indirect
 	github.com/ebitengine/hideconsole v1.0.0 // indirect
-	github.com/ebitengine/purego v0.9.0 // indirect
-	github.com/icza/mjpeg v0.0.0-20230330134156-38318e5ab8f4 // indirect
-	github.com/jezek/xgb v1.1.1 // indirect
+	github.com/ebitengine/oto/v3 v3.4.0 // indirect
 	github.com/quasilyte/gmath v0.0.0-20221217210116-fba37a2e15c7 // indirect
 	golang.org/x/exp v0.0.0-20231006140011-7918f672742d // indirect
-	golang.org/x/image v0.31.0 // indirect
 	golang.org/x/sync v0.17.0 // indirect
 	golang.org/x/sys v0.36.0 // indirect
+	golang.org/x/text v0.29.0 // indirect
 )
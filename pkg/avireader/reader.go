@@ -0,0 +1,381 @@
+package avireader
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"main/pkg/riff"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrNotAVI is returned for input that isn't a RIFF AVI file
+var ErrNotAVI = errors.New("avireader: not an AVI file")
+
+// ErrNoVideo is returned for an AVI without a video stream
+var ErrNoVideo = errors.New("avireader: no video stream")
+
+// AudioChunk is a PCM chunk of the audio stream
+type AudioChunk struct {
+	riff.Chunk
+
+	// After is the number of video frames before it in the file, so
+	// tools can keep audio with the frames it was written after
+	After int
+}
+
+// Reader reads the frames of an MJPEG AVI, such as MJPEGRecorder writes
+// Frames are found through the idx1 index when there is a usable one and
+// by scanning the movi list when not, so a recording that was never
+// finalized still yields the frames written before it stopped
+type Reader struct {
+	Width, Height int
+	FPS           float64
+
+	// Rate and Scale are the frame rate as the file stores it, the
+	// fraction Rate/Scale, so a writer can keep it exactly
+	Rate, Scale int
+
+	// Frames are the video chunks, in order; each holds one JPEG
+	Frames []riff.Chunk
+
+	// AudioRate, AudioChannels and AudioBits describe the PCM stream; a
+	// rate of 0 means there is none
+	AudioRate     int
+	AudioChannels int
+	AudioBits     int
+	Audio         []AudioChunk
+
+	// Indexed reports whether the frames came from idx1
+	Indexed bool
+
+	// Truncated reports that the file ends early, e.g. because the
+	// writer never finalized it; the frames that fit are still read
+	Truncated bool
+
+	file  *riff.File
+	f     *os.File // set by Open
+	video string   // chunk ID prefix of the video stream, e.g. "00"
+	audio string
+	next  int
+}
+
+// Open opens the AVI file at path; Close it when done
+func Open(path string) (*Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r, err := New(f, stat.Size())
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.f = f
+	return r, nil
+}
+
+// New reads the headers and frame table of the AVI in ra
+func New(ra io.ReaderAt, size int64) (*Reader, error) {
+	file, err := riff.Open(ra)
+	if err != nil || file.Form != "AVI " {
+		return nil, ErrNotAVI
+	}
+	r := &Reader{file: file, Truncated: file.Size != size}
+	chunks, err := file.Chunks(size)
+	if errors.Is(err, riff.ErrTruncated) {
+		r.Truncated = true
+	} else if err != nil {
+		return nil, fmt.Errorf("avireader: %w", err)
+	}
+
+	hdrl, ok := riff.Find(chunks, "hdrl")
+	if !ok {
+		return nil, errors.New("avireader: no hdrl list")
+	}
+	if err := r.readHeaders(hdrl); err != nil {
+		return nil, err
+	}
+	movi, ok := riff.Find(chunks, "movi")
+	if !ok {
+		return nil, errors.New("avireader: no movi list")
+	}
+	if movi.Size < 4 || movi.Offset+int64(movi.Size) > size {
+		// Unfinalized or cut short: the frames run to the end of the file
+		movi.Size = uint32(size - movi.Offset)
+		r.Truncated = true
+	}
+
+	if idx1, ok := riff.Find(chunks, "idx1"); ok && !r.Truncated {
+		r.Indexed = r.readIndex(idx1, movi) == nil
+	}
+	if !r.Indexed {
+		if err := r.scan(movi); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// readHeaders reads the frame size, frame rate and stream formats
+func (r *Reader) readHeaders(hdrl riff.Chunk) error {
+	chunks, err := r.file.List(hdrl)
+	if err != nil {
+		return fmt.Errorf("avireader: %w", err)
+	}
+	le := binary.LittleEndian
+	if avih, ok := riff.Find(chunks, "avih"); ok {
+		data, err := r.file.Data(avih)
+		if err != nil {
+			return fmt.Errorf("avireader: %w", err)
+		}
+		if len(data) >= 40 {
+			if us := le.Uint32(data[0:]); us > 0 {
+				r.FPS = 1e6 / float64(us)
+				r.Rate, r.Scale = 1000000, int(us)
+			}
+			r.Width, r.Height = int(le.Uint32(data[32:])), int(le.Uint32(data[36:]))
+		}
+	}
+
+	r.video, r.audio = "", ""
+	stream := 0
+	for _, c := range chunks {
+		if c.ID != "LIST" || c.Kind != "strl" {
+			continue
+		}
+		id := fmt.Sprintf("%02d", stream)
+		stream++
+		strl, err := r.file.List(c)
+		if err != nil {
+			return fmt.Errorf("avireader: %w", err)
+		}
+		strh, ok := riff.Find(strl, "strh")
+		if !ok {
+			continue
+		}
+		h, err := r.file.Data(strh)
+		if err != nil || len(h) < 36 {
+			return errors.New("avireader: bad strh header")
+		}
+		var strf []byte
+		if c, ok := riff.Find(strl, "strf"); ok {
+			strf, _ = r.file.Data(c)
+		}
+		switch string(h[0:4]) {
+		case "vids":
+			if r.video != "" {
+				continue
+			}
+			r.video = id
+			if scale := le.Uint32(h[20:]); scale > 0 && le.Uint32(h[24:]) > 0 {
+				r.FPS = float64(le.Uint32(h[24:])) / float64(scale)
+				r.Rate, r.Scale = int(le.Uint32(h[24:])), int(scale)
+			}
+			if len(strf) >= 20 {
+				if fourcc := strings.ToUpper(string(strf[16:20])); fourcc != "MJPG" && fourcc != "JPEG" {
+					return fmt.Errorf("avireader: unsupported video compression %q", strf[16:20])
+				}
+				if r.Width == 0 {
+					r.Width, r.Height = int(le.Uint32(strf[4:])), int(le.Uint32(strf[8:]))
+				}
+			}
+		case "auds":
+			if r.audio != "" || len(strf) < 16 || le.Uint16(strf[0:]) != 1 { // WAVE_FORMAT_PCM
+				continue
+			}
+			r.audio = id
+			r.AudioChannels = int(le.Uint16(strf[2:]))
+			r.AudioRate = int(le.Uint32(strf[4:]))
+			r.AudioBits = int(le.Uint16(strf[14:]))
+		}
+	}
+	if r.video == "" {
+		return ErrNoVideo
+	}
+	if r.FPS <= 0 {
+		return errors.New("avireader: no frame rate")
+	}
+	return nil
+}
+
+// readIndex builds the frame table from idx1
+// It fails if any entry doesn't point at a matching chunk in movi, and
+// New falls back to scanning
+func (r *Reader) readIndex(idx1, movi riff.Chunk) error {
+	data, err := r.file.Data(idx1)
+	if err != nil {
+		return err
+	}
+	le := binary.LittleEndian
+	moviEnd := movi.Offset + int64(movi.Size)
+
+	// Offsets are relative to the movi list type by convention, but some
+	// writers use file offsets; the first entry tells which
+	base := movi.Offset
+	if len(data) >= 16 && int64(le.Uint32(data[8:])) >= movi.Offset {
+		base = 0
+	}
+	var frames []riff.Chunk
+	var audio []AudioChunk
+	for e := data; len(e) >= 16; e = e[16:] {
+		id := string(e[0:4])
+		video, sound := r.isVideo(id), r.isAudio(id)
+		if !video && !sound {
+			continue
+		}
+		c, err := r.file.ChunkAt(base + int64(le.Uint32(e[8:])))
+		if err != nil || c.ID != id || c.Size != le.Uint32(e[12:]) || c.Offset+int64(c.Size) > moviEnd {
+			return errors.New("avireader: idx1 entry doesn't match its chunk")
+		}
+		if video {
+			frames = append(frames, c)
+		} else {
+			audio = append(audio, AudioChunk{Chunk: c, After: len(frames)})
+		}
+	}
+	if len(frames) == 0 {
+		return errors.New("avireader: idx1 has no frames")
+	}
+	r.Frames, r.Audio = frames, audio
+	return nil
+}
+
+// scan builds the frame table by walking movi, rec lists included
+// A chunk cut short by the end of the file is dropped
+func (r *Reader) scan(movi riff.Chunk) error {
+	r.Frames, r.Audio = nil, nil
+	var last riff.Chunk
+	var add func(c riff.Chunk) error
+	add = func(c riff.Chunk) error {
+		if c.ID == "LIST" {
+			return r.file.Each(c, add)
+		}
+		last = c
+		switch {
+		case r.isVideo(c.ID):
+			r.Frames = append(r.Frames, c)
+		case r.isAudio(c.ID):
+			r.Audio = append(r.Audio, AudioChunk{Chunk: c, After: len(r.Frames)})
+		}
+		return nil
+	}
+	err := r.file.Each(movi, add)
+	if errors.Is(err, riff.ErrTruncated) {
+		// The last chunk seen is the one that was cut
+		r.Truncated = true
+		if n := len(r.Frames); n > 0 && r.Frames[n-1] == last {
+			r.Frames = r.Frames[:n-1]
+		}
+		if n := len(r.Audio); n > 0 && r.Audio[n-1].Chunk == last {
+			r.Audio = r.Audio[:n-1]
+		}
+		err = nil
+	}
+	if err != nil {
+		return fmt.Errorf("avireader: %w", err)
+	}
+	if len(r.Frames) == 0 {
+		return errors.New("avireader: no frames")
+	}
+	return nil
+}
+
+func (r *Reader) isVideo(id string) bool {
+	return strings.HasPrefix(id, r.video) && (strings.HasSuffix(id, "dc") || strings.HasSuffix(id, "db"))
+}
+
+func (r *Reader) isAudio(id string) bool {
+	return r.audio != "" && id == r.audio+"wb"
+}
+
+// Len returns the number of frames
+func (r *Reader) Len() int {
+	return len(r.Frames)
+}
+
+// Duration returns the video's length at its frame rate
+func (r *Reader) Duration() time.Duration {
+	return r.Time(len(r.Frames))
+}
+
+// Time returns when frame i is shown
+func (r *Reader) Time(i int) time.Duration {
+	return time.Duration(float64(i) / r.FPS * float64(time.Second))
+}
+
+// FrameAt returns the index of the frame shown at t, clamped to the
+// frames there are
+func (r *Reader) FrameAt(t time.Duration) int {
+	i := int(t.Seconds()*r.FPS + 1e-6) // Time rounds down to the nanosecond
+	return min(max(i, 0), len(r.Frames)-1)
+}
+
+// JPEG returns frame i's JPEG data as stored, without decoding it
+func (r *Reader) JPEG(i int) ([]byte, error) {
+	if i < 0 || i >= len(r.Frames) {
+		return nil, fmt.Errorf("avireader: frame %d out of range (%d frames)", i, len(r.Frames))
+	}
+	data, err := r.file.Data(r.Frames[i])
+	if err != nil {
+		return nil, fmt.Errorf("avireader: frame %d: %w", i, err)
+	}
+	return data, nil
+}
+
+// Frame decodes frame i
+func (r *Reader) Frame(i int) (image.Image, error) {
+	data, err := r.JPEG(i)
+	if err != nil {
+		return nil, err
+	}
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("avireader: frame %d: %w", i, err)
+	}
+	return img, nil
+}
+
+// PCM returns an audio chunk's samples as stored
+func (r *Reader) PCM(c AudioChunk) ([]byte, error) {
+	return r.file.Data(c.Chunk)
+}
+
+// Next decodes the next frame and returns it with its timestamp; it
+// returns io.EOF after the last frame
+func (r *Reader) Next() (image.Image, time.Duration, error) {
+	if r.next >= len(r.Frames) {
+		return nil, 0, io.EOF
+	}
+	i := r.next
+	img, err := r.Frame(i)
+	if err != nil {
+		return nil, 0, err
+	}
+	r.next++
+	return img, r.Time(i), nil
+}
+
+// Skip moves past the next frame without decoding it, e.g. after Next
+// failed on it
+func (r *Reader) Skip() {
+	r.next = min(r.next+1, len(r.Frames))
+}
+
+// Close closes the file if the Reader was opened with Open
+func (r *Reader) Close() error {
+	if r.f == nil {
+		return nil
+	}
+	return r.f.Close()
+}
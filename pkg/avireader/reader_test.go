@@ -0,0 +1,179 @@
+package avireader
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"main/pkg/recorder/encode"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// frameColor is frame i's color, far enough from its neighbours to tell
+// them apart after JPEG
+func frameColor(i int) color.RGBA {
+	return color.RGBA{uint8(i * 40 % 256), uint8(255 - i*40%256), 128, 255}
+}
+
+// writeAVI records n solid frames with the writer MJPEGRecorder uses,
+// with a chunk of silence after every frame if audioRate is set
+func writeAVI(t *testing.T, n, fps, audioRate int) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.avi")
+	w, err := encode.NewAVIWriter(path, 64, 48, fps, audioRate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for i := range n {
+		c := frameColor(i)
+		for p := 0; p < len(img.Pix); p += 4 {
+			img.Pix[p], img.Pix[p+1], img.Pix[p+2], img.Pix[p+3] = c.R, c.G, c.B, c.A
+		}
+		data, err := encode.JPEG(img, 90)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.AddFrame(data); err != nil {
+			t.Fatal(err)
+		}
+		if audioRate > 0 {
+			if err := w.AddAudio(make([]byte, 4*audioRate/fps)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// checkFrames decodes every frame and checks its color
+func checkFrames(t *testing.T, r *Reader) {
+	t.Helper()
+	for i := range r.Len() {
+		img, err := r.Frame(i)
+		if err != nil {
+			t.Fatalf("frame %d: %v", i, err)
+		}
+		if img.Bounds().Dx() != 64 || img.Bounds().Dy() != 48 {
+			t.Fatalf("frame %d is %v, want 64x48", i, img.Bounds().Size())
+		}
+		got := color.RGBAModel.Convert(img.At(32, 24)).(color.RGBA)
+		if want := frameColor(i); !near(got, want) {
+			t.Errorf("frame %d is %v, want about %v", i, got, want)
+		}
+	}
+}
+
+func near(a, b color.RGBA) bool {
+	d := func(x, y uint8) int { return max(int(x)-int(y), int(y)-int(x)) }
+	return d(a.R, b.R) <= 8 && d(a.G, b.G) <= 8 && d(a.B, b.B) <= 8
+}
+
+func TestReadFinalized(t *testing.T) {
+	r, err := Open(writeAVI(t, 12, 30, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if r.Len() != 12 || r.FPS != 30 || r.Width != 64 || r.Height != 48 {
+		t.Errorf("got %d frames at %v fps, %dx%d; want 12 at 30 fps, 64x48", r.Len(), r.FPS, r.Width, r.Height)
+	}
+	if !r.Indexed || r.Truncated {
+		t.Errorf("Indexed = %v, Truncated = %v; want an indexed, complete file", r.Indexed, r.Truncated)
+	}
+	if d := r.Duration(); d != 400*time.Millisecond {
+		t.Errorf("Duration() = %v, want 400ms", d)
+	}
+	if i := r.FrameAt(time.Second / 3); i != 10 {
+		t.Errorf("FrameAt(1/3s) = %d, want 10", i)
+	}
+	checkFrames(t, r)
+}
+
+func TestReadAudio(t *testing.T) {
+	r, err := Open(writeAVI(t, 5, 25, 44100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if r.Len() != 5 || r.AudioRate != 44100 || r.AudioChannels != 2 || r.AudioBits != 16 {
+		t.Errorf("got %d frames, %d Hz, %d channels, %d bits; want 5 frames of 44100 Hz 16-bit stereo", r.Len(), r.AudioRate, r.AudioChannels, r.AudioBits)
+	}
+	if len(r.Audio) == 0 {
+		t.Fatal("no audio chunks")
+	}
+	if after := r.Audio[0].After; after != 1 {
+		t.Errorf("first audio chunk after %d frames, want 1", after)
+	}
+	checkFrames(t, r)
+}
+
+func TestReadTruncated(t *testing.T) {
+	data, err := os.ReadFile(writeAVI(t, 16, 30, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Cut the file in the middle of the last frame, losing idx1
+	idx1 := bytes.LastIndex(data, []byte("idx1"))
+	if idx1 < 0 {
+		t.Fatal("no idx1 chunk")
+	}
+	cut := data[:idx1-20]
+
+	r, err := New(bytes.NewReader(cut), int64(len(cut)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Len() != 15 {
+		t.Errorf("got %d frames, want the 15 that are whole", r.Len())
+	}
+	if r.Indexed || !r.Truncated {
+		t.Errorf("Indexed = %v, Truncated = %v; want a scanned, truncated file", r.Indexed, r.Truncated)
+	}
+	if r.FPS != 30 || r.Width != 64 || r.Height != 48 {
+		t.Errorf("got %v fps, %dx%d; want 30 fps, 64x48", r.FPS, r.Width, r.Height)
+	}
+	checkFrames(t, r)
+}
+
+func TestNotAVI(t *testing.T) {
+	data := []byte("RIFF\x04\x00\x00\x00WEBP")
+	if _, err := New(bytes.NewReader(data), int64(len(data))); !errors.Is(err, ErrNotAVI) {
+		t.Errorf("err = %v, want ErrNotAVI", err)
+	}
+}
+
+func TestReadFractionalRate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ntsc.avi")
+	w, err := encode.NewAVIWriterRate(path, 64, 48, 30000, 1001, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := encode.JPEG(image.NewRGBA(image.Rect(0, 0, 64, 48)), 90)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		if err := w.AddFrame(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if r.Rate != 30000 || r.Scale != 1001 || r.FPS != 30000.0/1001 {
+		t.Errorf("rate %d/%d, %v fps; want 30000/1001", r.Rate, r.Scale, r.FPS)
+	}
+}
@@ -0,0 +1,202 @@
+package bot
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// defaultHold is how long press, click and tap steps hold the input
+const defaultHold = Duration(100 * time.Millisecond)
+
+// Bot plays a Script through an Injector, one game tick at a time
+// Timings are converted to ticks, so a script plays the same way
+// regardless of how fast frames are drawn
+type Bot struct {
+	script   *Script
+	inj      Injector
+	tick     int
+	tps      int
+	rng      *rand.Rand
+	releases []release
+	keys     map[ebiten.Key]bool
+	buttons  map[ebiten.MouseButton]bool
+	layoutW  int
+	layoutH  int
+}
+
+// release is a pending key or button release
+type release struct {
+	tick     int
+	key      ebiten.Key
+	button   ebiten.MouseButton
+	isButton bool
+}
+
+// New creates a bot that plays script through inj
+func New(script *Script, inj Injector) *Bot {
+	var seed uint64
+	if script.Random != nil {
+		seed = script.Random.Seed
+	}
+	return &Bot{
+		script:  script,
+		inj:     inj,
+		tps:     ebiten.TPS(),
+		rng:     rand.New(rand.NewPCG(seed, seed)),
+		keys:    map[ebiten.Key]bool{},
+		buttons: map[ebiten.MouseButton]bool{},
+	}
+}
+
+// Update injects the events due on this tick
+// Call it once per game Update; vp maps game to screen coordinates
+// layoutW and layoutH are the game's Layout size, used as the default
+// random click area
+func (b *Bot) Update(vp Viewport, layoutW, layoutH int) error {
+	b.layoutW, b.layoutH = layoutW, layoutH
+	t := b.tick
+	b.tick++
+
+	var firstErr error
+	check := func(err error) {
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	// Pending releases first, so a press and its re-press can share a tick
+	kept := b.releases[:0]
+	for _, r := range b.releases {
+		if r.tick > t {
+			kept = append(kept, r)
+			continue
+		}
+		if r.isButton {
+			check(b.buttonUp(r.button))
+		} else {
+			check(b.keyUp(r.key))
+		}
+	}
+	b.releases = kept
+
+	for _, step := range b.script.Steps {
+		if b.fires(step, t) {
+			check(b.apply(step, t, vp))
+		}
+	}
+
+	if r := b.script.Random; r != nil {
+		from := b.ticks(r.From)
+		if every := max(b.ticks(r.Interval), 1); t >= from && (t-from)%every == 0 {
+			check(b.randomStep(r, t, vp))
+		}
+	}
+
+	return firstErr
+}
+
+// fires reports whether step is due on tick t
+func (b *Bot) fires(step Step, t int) bool {
+	at := b.ticks(step.At)
+	if t == at {
+		return true
+	}
+	if b.script.Loop <= 0 || step.At < b.script.LoopFrom || t < at {
+		return false
+	}
+	return (t-at)%max(b.ticks(b.script.Loop), 1) == 0
+}
+
+func (b *Bot) apply(step Step, t int, vp Viewport) error {
+	hold := step.Hold
+	if hold == 0 {
+		hold = defaultHold
+	}
+	switch step.Type {
+	case StepKeyDown:
+		return b.keyDown(step.Key)
+	case StepKeyUp:
+		return b.keyUp(step.Key)
+	case StepKeyPress:
+		b.releases = append(b.releases, release{tick: t + max(b.ticks(hold), 1), key: step.Key})
+		return b.keyDown(step.Key)
+	case StepMouseMove:
+		return b.inj.MouseMove(vp.toScreen(step.X, step.Y))
+	case StepClick, StepTap:
+		// Desktop OSes can't synthesize touches for ebiten, so taps are
+		// delivered as left clicks
+		button := step.Button
+		if step.Type == StepTap {
+			button = ebiten.MouseButtonLeft
+		}
+		if err := b.inj.MouseMove(vp.toScreen(step.X, step.Y)); err != nil {
+			return err
+		}
+		b.releases = append(b.releases, release{tick: t + max(b.ticks(hold), 1), button: button, isButton: true})
+		return b.buttonDown(button)
+	}
+	return nil
+}
+
+func (b *Bot) randomStep(r *RandomWalk, t int, vp Viewport) error {
+	n := len(r.Keys)
+	if r.Clicks {
+		n++
+	}
+	step := Step{Type: StepKeyPress, Hold: r.Hold}
+	if i := b.rng.IntN(n); i < len(r.Keys) {
+		step.Key = r.Keys[i]
+	} else {
+		w, h := r.Area[0], r.Area[1]
+		if w <= 0 || h <= 0 {
+			w, h = b.layoutW, b.layoutH
+		}
+		step.Type = StepClick
+		step.X, step.Y = b.rng.IntN(max(w, 1)), b.rng.IntN(max(h, 1))
+	}
+	return b.apply(step, t, vp)
+}
+
+func (b *Bot) keyDown(k ebiten.Key) error {
+	b.keys[k] = true
+	return b.inj.KeyDown(k)
+}
+
+func (b *Bot) keyUp(k ebiten.Key) error {
+	delete(b.keys, k)
+	return b.inj.KeyUp(k)
+}
+
+func (b *Bot) buttonDown(button ebiten.MouseButton) error {
+	b.buttons[button] = true
+	return b.inj.MouseDown(button)
+}
+
+func (b *Bot) buttonUp(button ebiten.MouseButton) error {
+	delete(b.buttons, button)
+	return b.inj.MouseUp(button)
+}
+
+// ticks converts d to game ticks
+func (b *Bot) ticks(d Duration) int {
+	return int(math.Round(time.Duration(d).Seconds() * float64(b.tps)))
+}
+
+// Close releases every key and button the bot still holds and closes
+// the injector
+// Always call it, otherwise the OS keeps those inputs pressed
+func (b *Bot) Close() error {
+	for k := range b.keys {
+		b.inj.KeyUp(k)
+	}
+	for button := range b.buttons {
+		b.inj.MouseUp(button)
+	}
+	b.keys = map[ebiten.Key]bool{}
+	b.buttons = map[ebiten.MouseButton]bool{}
+	b.releases = nil
+	return b.inj.Close()
+}
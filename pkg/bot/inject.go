@@ -0,0 +1,60 @@
+package bot
+
+import (
+	"errors"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Injector sends synthetic input to the OS, which delivers it to the
+// focused game window like real input
+// It drives the whole desktop: whichever window has focus gets the input,
+// so nothing else should run alongside a scripted game
+// Mouse coordinates are screen coordinates; see Viewport
+type Injector interface {
+	KeyDown(k ebiten.Key) error
+	KeyUp(k ebiten.Key) error
+	MouseMove(x, y int) error
+	MouseDown(b ebiten.MouseButton) error
+	MouseUp(b ebiten.MouseButton) error
+	Close() error
+}
+
+// ErrUnsupported is returned by NewInjector on platforms without a backend
+var ErrUnsupported = errors.New("bot: synthetic input is not supported on this platform")
+
+// ErrUnknownKey is returned when a key has no mapping on this platform
+var ErrUnknownKey = errors.New("bot: key not supported by the injector")
+
+// Viewport maps game (Layout) coordinates to screen coordinates
+type Viewport struct {
+	X, Y  float64 // screen position of the game's top-left corner
+	Scale float64 // screen units per game pixel
+}
+
+func (v Viewport) toScreen(x, y int) (int, int) {
+	return int(math.Round(v.X + float64(x)*v.Scale)), int(math.Round(v.Y + float64(y)*v.Scale))
+}
+
+// WindowViewport computes the viewport of the running game window
+// layoutW and layoutH are the size returned by the game's Layout
+// The window is assumed to be on the primary monitor; ebiten centers
+// the game inside the window when the aspect ratios differ
+func WindowViewport(layoutW, layoutH int) Viewport {
+	wx, wy := ebiten.WindowPosition()
+	ww, wh := ebiten.WindowSize()
+	v := Viewport{X: float64(wx), Y: float64(wy), Scale: 1}
+	if layoutW > 0 && layoutH > 0 && ww > 0 && wh > 0 {
+		v.Scale = math.Min(float64(ww)/float64(layoutW), float64(wh)/float64(layoutH))
+		v.X += (float64(ww) - float64(layoutW)*v.Scale) / 2
+		v.Y += (float64(wh) - float64(layoutH)*v.Scale) / 2
+	}
+	if screenUsesDevicePixels {
+		s := ebiten.Monitor().DeviceScaleFactor()
+		v.X *= s
+		v.Y *= s
+		v.Scale *= s
+	}
+	return v
+}
@@ -0,0 +1,162 @@
+//go:build darwin && !ios
+
+package bot
+
+import (
+	"github.com/ebitengine/purego"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Quartz event coordinates are points, the same units ebiten reports
+// window positions in
+const screenUsesDevicePixels = false
+
+const (
+	kCGHIDEventTap = 0
+
+	kCGEventLeftMouseDown  = 1
+	kCGEventLeftMouseUp    = 2
+	kCGEventRightMouseDown = 3
+	kCGEventRightMouseUp   = 4
+	kCGEventMouseMoved     = 5
+	kCGEventOtherMouseDown = 25
+	kCGEventOtherMouseUp   = 26
+
+	kCGMouseButtonLeft   = 0
+	kCGMouseButtonRight  = 1
+	kCGMouseButtonCenter = 2
+)
+
+type cgPoint struct {
+	X, Y float64
+}
+
+var (
+	cgEventCreateKeyboardEvent func(source uintptr, keycode uint16, keyDown bool) uintptr
+	cgEventCreateMouseEvent    func(source uintptr, typ uint32, pos cgPoint, button uint32) uintptr
+	cgEventPost                func(tap uint32, event uintptr)
+	cfRelease                  func(ref uintptr)
+)
+
+// darwinInjector posts Quartz events through CoreGraphics
+// The process needs the Accessibility permission for the events to
+// reach other applications, including its own window
+type darwinInjector struct {
+	pos cgPoint
+}
+
+// NewInjector returns the CoreGraphics backend
+func NewInjector() (Injector, error) {
+	if cgEventPost == nil {
+		cg, err := purego.Dlopen("/System/Library/Frameworks/CoreGraphics.framework/CoreGraphics", purego.RTLD_NOW|purego.RTLD_GLOBAL)
+		if err != nil {
+			return nil, err
+		}
+		cf, err := purego.Dlopen("/System/Library/Frameworks/CoreFoundation.framework/CoreFoundation", purego.RTLD_NOW|purego.RTLD_GLOBAL)
+		if err != nil {
+			return nil, err
+		}
+		purego.RegisterLibFunc(&cgEventCreateKeyboardEvent, cg, "CGEventCreateKeyboardEvent")
+		purego.RegisterLibFunc(&cgEventCreateMouseEvent, cg, "CGEventCreateMouseEvent")
+		purego.RegisterLibFunc(&cfRelease, cf, "CFRelease")
+		purego.RegisterLibFunc(&cgEventPost, cg, "CGEventPost")
+	}
+	return &darwinInjector{}, nil
+}
+
+func (d *darwinInjector) post(event uintptr) {
+	cgEventPost(kCGHIDEventTap, event)
+	cfRelease(event)
+}
+
+func (d *darwinInjector) key(k ebiten.Key, down bool) error {
+	code, ok := darwinKeycodes[k]
+	if !ok {
+		return ErrUnknownKey
+	}
+	d.post(cgEventCreateKeyboardEvent(0, code, down))
+	return nil
+}
+
+func (d *darwinInjector) KeyDown(k ebiten.Key) error {
+	return d.key(k, true)
+}
+
+func (d *darwinInjector) KeyUp(k ebiten.Key) error {
+	return d.key(k, false)
+}
+
+func (d *darwinInjector) MouseMove(x, y int) error {
+	d.pos = cgPoint{X: float64(x), Y: float64(y)}
+	d.post(cgEventCreateMouseEvent(0, kCGEventMouseMoved, d.pos, kCGMouseButtonLeft))
+	return nil
+}
+
+func (d *darwinInjector) MouseDown(b ebiten.MouseButton) error {
+	switch b {
+	case ebiten.MouseButtonRight:
+		d.post(cgEventCreateMouseEvent(0, kCGEventRightMouseDown, d.pos, kCGMouseButtonRight))
+	case ebiten.MouseButtonMiddle:
+		d.post(cgEventCreateMouseEvent(0, kCGEventOtherMouseDown, d.pos, kCGMouseButtonCenter))
+	default:
+		d.post(cgEventCreateMouseEvent(0, kCGEventLeftMouseDown, d.pos, kCGMouseButtonLeft))
+	}
+	return nil
+}
+
+func (d *darwinInjector) MouseUp(b ebiten.MouseButton) error {
+	switch b {
+	case ebiten.MouseButtonRight:
+		d.post(cgEventCreateMouseEvent(0, kCGEventRightMouseUp, d.pos, kCGMouseButtonRight))
+	case ebiten.MouseButtonMiddle:
+		d.post(cgEventCreateMouseEvent(0, kCGEventOtherMouseUp, d.pos, kCGMouseButtonCenter))
+	default:
+		d.post(cgEventCreateMouseEvent(0, kCGEventLeftMouseUp, d.pos, kCGMouseButtonLeft))
+	}
+	return nil
+}
+
+func (d *darwinInjector) Close() error {
+	return nil
+}
+
+// darwinKeycodes maps ebiten keys to macOS virtual keycodes (ANSI layout)
+var darwinKeycodes = map[ebiten.Key]uint16{
+	ebiten.KeyA: 0x00, ebiten.KeyS: 0x01, ebiten.KeyD: 0x02, ebiten.KeyF: 0x03,
+	ebiten.KeyH: 0x04, ebiten.KeyG: 0x05, ebiten.KeyZ: 0x06, ebiten.KeyX: 0x07,
+	ebiten.KeyC: 0x08, ebiten.KeyV: 0x09, ebiten.KeyB: 0x0b, ebiten.KeyQ: 0x0c,
+	ebiten.KeyW: 0x0d, ebiten.KeyE: 0x0e, ebiten.KeyR: 0x0f, ebiten.KeyY: 0x10,
+	ebiten.KeyT: 0x11, ebiten.KeyO: 0x1f, ebiten.KeyU: 0x20, ebiten.KeyI: 0x22,
+	ebiten.KeyP: 0x23, ebiten.KeyL: 0x25, ebiten.KeyJ: 0x26, ebiten.KeyK: 0x28,
+	ebiten.KeyN: 0x2d, ebiten.KeyM: 0x2e,
+
+	ebiten.KeyDigit1: 0x12, ebiten.KeyDigit2: 0x13, ebiten.KeyDigit3: 0x14,
+	ebiten.KeyDigit4: 0x15, ebiten.KeyDigit5: 0x17, ebiten.KeyDigit6: 0x16,
+	ebiten.KeyDigit7: 0x1a, ebiten.KeyDigit8: 0x1c, ebiten.KeyDigit9: 0x19,
+	ebiten.KeyDigit0: 0x1d,
+
+	ebiten.KeyF1: 0x7a, ebiten.KeyF2: 0x78, ebiten.KeyF3: 0x63, ebiten.KeyF4: 0x76,
+	ebiten.KeyF5: 0x60, ebiten.KeyF6: 0x61, ebiten.KeyF7: 0x62, ebiten.KeyF8: 0x64,
+	ebiten.KeyF9: 0x65, ebiten.KeyF10: 0x6d, ebiten.KeyF11: 0x67, ebiten.KeyF12: 0x6f,
+
+	ebiten.KeySpace:        0x31,
+	ebiten.KeyEnter:        0x24,
+	ebiten.KeyEscape:       0x35,
+	ebiten.KeyTab:          0x30,
+	ebiten.KeyBackspace:    0x33,
+	ebiten.KeyDelete:       0x75,
+	ebiten.KeyArrowLeft:    0x7b,
+	ebiten.KeyArrowRight:   0x7c,
+	ebiten.KeyArrowDown:    0x7d,
+	ebiten.KeyArrowUp:      0x7e,
+	ebiten.KeyShiftLeft:    0x38,
+	ebiten.KeyShiftRight:   0x3c,
+	ebiten.KeyControlLeft:  0x3b,
+	ebiten.KeyControlRight: 0x3e,
+	ebiten.KeyAltLeft:      0x3a,
+	ebiten.KeyAltRight:     0x3d,
+	ebiten.KeyComma:        0x2b,
+	ebiten.KeyPeriod:       0x2f,
+	ebiten.KeyMinus:        0x1b,
+	ebiten.KeyEqual:        0x18,
+}
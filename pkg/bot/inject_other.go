@@ -0,0 +1,10 @@
+//go:build !windows && !(darwin && !ios) && !((linux || freebsd || netbsd || openbsd) && !android)
+
+package bot
+
+const screenUsesDevicePixels = false
+
+// NewInjector returns ErrUnsupported; this platform has no backend
+func NewInjector() (Injector, error) {
+	return nil, ErrUnsupported
+}
@@ -0,0 +1,187 @@
+package bot
+
+import (
+	"syscall"
+	"unsafe"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Windows screen coordinates are physical pixels for DPI-aware
+// processes, which ebiten games are
+const screenUsesDevicePixels = true
+
+var (
+	user32             = syscall.NewLazyDLL("user32.dll")
+	procSendInput      = user32.NewProc("SendInput")
+	procSetCursorPos   = user32.NewProc("SetCursorPos")
+	procMapVirtualKeyW = user32.NewProc("MapVirtualKeyW")
+)
+
+const (
+	inputMouse    = 0
+	inputKeyboard = 1
+
+	keyeventfExtendedKey = 0x0001
+	keyeventfKeyUp       = 0x0002
+
+	mouseeventfLeftDown   = 0x0002
+	mouseeventfLeftUp     = 0x0004
+	mouseeventfRightDown  = 0x0008
+	mouseeventfRightUp    = 0x0010
+	mouseeventfMiddleDown = 0x0020
+	mouseeventfMiddleUp   = 0x0040
+)
+
+// input mirrors the Win32 INPUT struct: a type, then a union whose
+// largest member is MOUSEINPUT. Its pointer-sized field aligns the union
+// after the type, as the C compiler does
+type input struct {
+	typ uint32
+	mi  mouseInput
+}
+
+type mouseInput struct {
+	dx          int32
+	dy          int32
+	mouseData   uint32
+	dwFlags     uint32
+	time        uint32
+	dwExtraInfo uintptr
+}
+
+// keyboardInput is KEYBDINPUT, the union's other member
+type keyboardInput struct {
+	wVk         uint16
+	wScan       uint16
+	dwFlags     uint32
+	time        uint32
+	dwExtraInfo uintptr
+}
+
+// inputSize is sizeof(INPUT): 40 bytes on 64-bit Windows, 28 on 32-bit
+const inputSize = 28 + 12*(unsafe.Sizeof(uintptr(0))/8)
+
+// SendInput rejects any other size, so check it when building
+var (
+	_ [unsafe.Sizeof(input{}) - inputSize]byte
+	_ [inputSize - unsafe.Sizeof(input{})]byte
+)
+
+// windowsInjector uses SendInput and SetCursorPos
+type windowsInjector struct{}
+
+// NewInjector returns the SendInput backend
+func NewInjector() (Injector, error) {
+	if err := procSendInput.Find(); err != nil {
+		return nil, err
+	}
+	return windowsInjector{}, nil
+}
+
+func (windowsInjector) key(k ebiten.Key, flags uint32) error {
+	vk, ok := windowsVirtualKey(k)
+	if !ok {
+		return ErrUnknownKey
+	}
+	// GLFW reads the scancode, so fill it in alongside the virtual key
+	scan, _, _ := procMapVirtualKeyW.Call(uintptr(vk), 0)
+	switch k {
+	case ebiten.KeyArrowLeft, ebiten.KeyArrowUp, ebiten.KeyArrowRight, ebiten.KeyArrowDown,
+		ebiten.KeyDelete, ebiten.KeyControlRight, ebiten.KeyAltRight:
+		flags |= keyeventfExtendedKey
+	}
+	in := input{typ: inputKeyboard}
+	*(*keyboardInput)(unsafe.Pointer(&in.mi)) = keyboardInput{wVk: vk, wScan: uint16(scan), dwFlags: flags}
+	return sendInput(&in)
+}
+
+func (w windowsInjector) KeyDown(k ebiten.Key) error {
+	return w.key(k, 0)
+}
+
+func (w windowsInjector) KeyUp(k ebiten.Key) error {
+	return w.key(k, keyeventfKeyUp)
+}
+
+func (windowsInjector) MouseMove(x, y int) error {
+	if r, _, err := procSetCursorPos.Call(uintptr(x), uintptr(y)); r == 0 {
+		return err
+	}
+	return nil
+}
+
+func (windowsInjector) button(flags uint32) error {
+	in := input{typ: inputMouse, mi: mouseInput{dwFlags: flags}}
+	return sendInput(&in)
+}
+
+func (w windowsInjector) MouseDown(b ebiten.MouseButton) error {
+	switch b {
+	case ebiten.MouseButtonRight:
+		return w.button(mouseeventfRightDown)
+	case ebiten.MouseButtonMiddle:
+		return w.button(mouseeventfMiddleDown)
+	default:
+		return w.button(mouseeventfLeftDown)
+	}
+}
+
+func (w windowsInjector) MouseUp(b ebiten.MouseButton) error {
+	switch b {
+	case ebiten.MouseButtonRight:
+		return w.button(mouseeventfRightUp)
+	case ebiten.MouseButtonMiddle:
+		return w.button(mouseeventfMiddleUp)
+	default:
+		return w.button(mouseeventfLeftUp)
+	}
+}
+
+func (windowsInjector) Close() error {
+	return nil
+}
+
+func sendInput(in *input) error {
+	if n, _, err := procSendInput.Call(1, uintptr(unsafe.Pointer(in)), unsafe.Sizeof(*in)); n != 1 {
+		return err
+	}
+	return nil
+}
+
+// windowsVirtualKey maps ebiten keys to Win32 virtual-key codes
+func windowsVirtualKey(k ebiten.Key) (uint16, bool) {
+	switch {
+	case k >= ebiten.KeyA && k <= ebiten.KeyZ:
+		return uint16('A' + (k - ebiten.KeyA)), true
+	case k >= ebiten.KeyDigit0 && k <= ebiten.KeyDigit9:
+		return uint16('0' + (k - ebiten.KeyDigit0)), true
+	case k >= ebiten.KeyF1 && k <= ebiten.KeyF12:
+		return uint16(0x70 + (k - ebiten.KeyF1)), true
+	}
+	vk, ok := windowsVirtualKeys[k]
+	return vk, ok
+}
+
+var windowsVirtualKeys = map[ebiten.Key]uint16{
+	ebiten.KeySpace:        0x20,
+	ebiten.KeyEnter:        0x0d,
+	ebiten.KeyEscape:       0x1b,
+	ebiten.KeyTab:          0x09,
+	ebiten.KeyBackspace:    0x08,
+	ebiten.KeyDelete:       0x2e,
+	ebiten.KeyArrowLeft:    0x25,
+	ebiten.KeyArrowUp:      0x26,
+	ebiten.KeyArrowRight:   0x27,
+	ebiten.KeyArrowDown:    0x28,
+	ebiten.KeyShiftLeft:    0xa0,
+	ebiten.KeyShiftRight:   0xa1,
+	ebiten.KeyControlLeft:  0xa2,
+	ebiten.KeyControlRight: 0xa3,
+	ebiten.KeyAltLeft:      0xa4,
+	ebiten.KeyAltRight:     0xa5,
+	ebiten.KeyComma:        0xbc,
+	ebiten.KeyPeriod:       0xbe,
+	ebiten.KeyMinus:        0xbd,
+	ebiten.KeyEqual:        0xbb,
+}
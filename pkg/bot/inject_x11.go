@@ -0,0 +1,142 @@
+//go:build (linux || freebsd || netbsd || openbsd) && !android
+
+package bot
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xproto"
+	"github.com/jezek/xgb/xtest"
+)
+
+// X11 screen coordinates are device pixels
+const screenUsesDevicePixels = true
+
+// x11Injector uses the XTEST extension, the same mechanism as xdotool
+type x11Injector struct {
+	conn     *xgb.Conn
+	root     xproto.Window
+	keycodes map[xproto.Keysym]xproto.Keycode
+}
+
+// NewInjector connects to the X server named by $DISPLAY
+func NewInjector() (Injector, error) {
+	conn, err := xgb.NewConn()
+	if err != nil {
+		return nil, err
+	}
+	if err := xtest.Init(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	setup := xproto.Setup(conn)
+	inj := &x11Injector{
+		conn:     conn,
+		root:     setup.DefaultScreen(conn).Root,
+		keycodes: map[xproto.Keysym]xproto.Keycode{},
+	}
+
+	// Build the keysym -> keycode table from the server's keyboard mapping
+	first, last := setup.MinKeycode, setup.MaxKeycode
+	mapping, err := xproto.GetKeyboardMapping(conn, first, byte(last-first+1)).Reply()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	per := int(mapping.KeysymsPerKeycode)
+	for i := 0; per > 0 && i*per < len(mapping.Keysyms); i++ {
+		for _, sym := range mapping.Keysyms[i*per : (i+1)*per] {
+			if _, ok := inj.keycodes[sym]; !ok && sym != 0 {
+				inj.keycodes[sym] = first + xproto.Keycode(i)
+			}
+		}
+	}
+	return inj, nil
+}
+
+func (x *x11Injector) key(k ebiten.Key, event byte) error {
+	sym, ok := x11Keysym(k)
+	if !ok {
+		return ErrUnknownKey
+	}
+	code, ok := x.keycodes[sym]
+	if !ok {
+		return ErrUnknownKey
+	}
+	return xtest.FakeInputChecked(x.conn, event, byte(code), 0, x.root, 0, 0, 0).Check()
+}
+
+func (x *x11Injector) KeyDown(k ebiten.Key) error {
+	return x.key(k, xproto.KeyPress)
+}
+
+func (x *x11Injector) KeyUp(k ebiten.Key) error {
+	return x.key(k, xproto.KeyRelease)
+}
+
+func (x *x11Injector) MouseMove(px, py int) error {
+	return xtest.FakeInputChecked(x.conn, xproto.MotionNotify, 0, 0, x.root, int16(px), int16(py), 0).Check()
+}
+
+func (x *x11Injector) MouseDown(b ebiten.MouseButton) error {
+	return xtest.FakeInputChecked(x.conn, xproto.ButtonPress, x11Button(b), 0, x.root, 0, 0, 0).Check()
+}
+
+func (x *x11Injector) MouseUp(b ebiten.MouseButton) error {
+	return xtest.FakeInputChecked(x.conn, xproto.ButtonRelease, x11Button(b), 0, x.root, 0, 0, 0).Check()
+}
+
+func (x *x11Injector) Close() error {
+	x.conn.Close()
+	return nil
+}
+
+// x11Button maps ebiten buttons to X11 pointer buttons
+func x11Button(b ebiten.MouseButton) byte {
+	switch b {
+	case ebiten.MouseButtonRight:
+		return 3
+	case ebiten.MouseButtonMiddle:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// x11Keysym maps ebiten keys to X11 keysyms
+func x11Keysym(k ebiten.Key) (xproto.Keysym, bool) {
+	switch {
+	case k >= ebiten.KeyA && k <= ebiten.KeyZ:
+		return xproto.Keysym('a' + (k - ebiten.KeyA)), true
+	case k >= ebiten.KeyDigit0 && k <= ebiten.KeyDigit9:
+		return xproto.Keysym('0' + (k - ebiten.KeyDigit0)), true
+	case k >= ebiten.KeyF1 && k <= ebiten.KeyF12:
+		return xproto.Keysym(0xffbe + (k - ebiten.KeyF1)), true
+	}
+	sym, ok := x11Keysyms[k]
+	return sym, ok
+}
+
+var x11Keysyms = map[ebiten.Key]xproto.Keysym{
+	ebiten.KeySpace:        0x0020,
+	ebiten.KeyEnter:        0xff0d,
+	ebiten.KeyEscape:       0xff1b,
+	ebiten.KeyTab:          0xff09,
+	ebiten.KeyBackspace:    0xff08,
+	ebiten.KeyDelete:       0xffff,
+	ebiten.KeyArrowLeft:    0xff51,
+	ebiten.KeyArrowUp:      0xff52,
+	ebiten.KeyArrowRight:   0xff53,
+	ebiten.KeyArrowDown:    0xff54,
+	ebiten.KeyShiftLeft:    0xffe1,
+	ebiten.KeyShiftRight:   0xffe2,
+	ebiten.KeyControlLeft:  0xffe3,
+	ebiten.KeyControlRight: 0xffe4,
+	ebiten.KeyAltLeft:      0xffe9,
+	ebiten.KeyAltRight:     0xffea,
+	ebiten.KeyComma:        0x002c,
+	ebiten.KeyPeriod:       0x002e,
+	ebiten.KeyMinus:        0x002d,
+	ebiten.KeyEqual:        0x003d,
+}
@@ -0,0 +1,114 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"main/pkg/settings"
+	"os"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Script describes the synthetic input for one game
+//
+// Example (flappy: flap every 400ms, start with a click):
+//
+//	{
+//	  "steps": [
+//	    {"at": "500ms", "type": "click", "x": 320, "y": 240},
+//	    {"at": "1s", "type": "press", "key": "Space"}
+//	  ],
+//	  "loop": "400ms",
+//	  "loop_from": "1s"
+//	}
+type Script struct {
+	// Steps is the timeline of input events
+	Steps []Step `json:"steps"`
+
+	// Loop repeats the steps at or after LoopFrom every Loop
+	// 0 plays the timeline once
+	Loop     Duration `json:"loop,omitempty"`
+	LoopFrom Duration `json:"loop_from,omitempty"`
+
+	// Random adds random key presses and clicks on top of the timeline
+	Random *RandomWalk `json:"random,omitempty"`
+}
+
+// Step types
+const (
+	StepKeyDown   = "down"  // hold Key until a matching "up"
+	StepKeyUp     = "up"    // release Key
+	StepKeyPress  = "press" // press Key for Hold
+	StepClick     = "click" // move to X,Y and click Button for Hold
+	StepMouseMove = "move"  // move to X,Y
+	StepTap       = "tap"   // touch at X,Y; delivered as a left click on desktops
+)
+
+// Step is one event in a Script timeline
+type Step struct {
+	At     Duration           `json:"at"`
+	Type   string             `json:"type"`
+	Key    ebiten.Key         `json:"key,omitempty"`    // ebiten key name, e.g. "Space", "ArrowLeft"
+	Button ebiten.MouseButton `json:"button,omitempty"` // 0 left, 1 right, 2 middle
+	X      int                `json:"x,omitempty"`      // game (Layout) coordinates
+	Y      int                `json:"y,omitempty"`
+	Hold   Duration           `json:"hold,omitempty"` // press/click duration, default 100ms
+}
+
+// RandomWalk presses a random key from Keys every Interval
+// If Clicks is set, some of the presses are random clicks inside Area
+// The same Seed always produces the same sequence
+type RandomWalk struct {
+	From     Duration     `json:"from,omitempty"`
+	Keys     []ebiten.Key `json:"keys,omitempty"`
+	Clicks   bool         `json:"clicks,omitempty"`
+	Area     [2]int       `json:"area,omitempty"` // width, height; defaults to the layout size
+	Interval Duration     `json:"interval"`
+	Hold     Duration     `json:"hold,omitempty"`
+	Seed     uint64       `json:"seed,omitempty"`
+}
+
+// Duration is a time.Duration written as a string such as "1.5s" in JSON
+type Duration = settings.Duration
+
+// LoadScript reads a JSON script from path
+func LoadScript(path string) (*Script, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s Script
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("bot: %s: %w", path, err)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, fmt.Errorf("bot: %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// Validate checks the script for unknown step types and bad timings
+func (s *Script) Validate() error {
+	for i, step := range s.Steps {
+		switch step.Type {
+		case StepKeyDown, StepKeyUp, StepKeyPress, StepClick, StepMouseMove, StepTap:
+		default:
+			return fmt.Errorf("step %d: unknown type %q", i, step.Type)
+		}
+		if step.At < 0 || step.Hold < 0 {
+			return fmt.Errorf("step %d: negative duration", i)
+		}
+	}
+	if s.Loop < 0 || s.LoopFrom < 0 {
+		return fmt.Errorf("negative loop duration")
+	}
+	if r := s.Random; r != nil {
+		if r.Interval <= 0 {
+			return fmt.Errorf("random: interval must be positive")
+		}
+		if len(r.Keys) == 0 && !r.Clicks {
+			return fmt.Errorf("random: needs keys or clicks")
+		}
+	}
+	return nil
+}
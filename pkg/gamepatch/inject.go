@@ -0,0 +1,193 @@
+package gamepatch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNothingPatched is returned when a game has no RunGame call to wrap
+var ErrNothingPatched = errors.New("gamepatch: no ebiten.RunGame or ebiten.RunGameWithOptions call to patch")
+
+// Injection builds a game so it records itself without touching its
+// sources: the patched files live in a work directory and replace the
+// originals through go build -overlay, and a go.work adds the recorder's
+// module next to the game's own, so the game keeps its module context
+// (its go.mod, its ebiten version) and needs no copy
+type Injection struct {
+	// Dir is the game's package directory; build and run from here
+	Dir string
+
+	// Overlay is the file for go build -overlay
+	Overlay string
+
+	// Work is the go.work for GOWORK, or "" when the game is part of
+	// the recorder's module already
+	Work string
+}
+
+// Inject patches the game in dir into workDir
+// root is the directory of the module that provides the recorder; if
+// the Options have no RecorderPath, it's that module's pkg/recorder
+func (p *Patcher) Inject(dir, workDir, root string) (*Injection, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if root, err = filepath.Abs(root); err != nil {
+		return nil, err
+	}
+	rootModule, goVersion, err := readGoMod(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return nil, err
+	}
+	if p.opts.RecorderPath == "" {
+		p.opts.RecorderPath = rootModule + "/pkg/recorder"
+	}
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return nil, err
+	}
+	if workDir, err = filepath.Abs(workDir); err != nil {
+		return nil, err
+	}
+
+	files, err := GoFiles([]string{dir})
+	if err != nil {
+		return nil, err
+	}
+	replace := map[string]string{}
+	for _, path := range files {
+		src, n, err := p.PatchFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			continue
+		}
+		patched := filepath.Join(workDir, filepath.Base(path))
+		if err := os.WriteFile(patched, src, 0644); err != nil {
+			return nil, err
+		}
+		replace[path] = patched
+	}
+	if len(replace) == 0 {
+		return nil, ErrNothingPatched
+	}
+
+	in := &Injection{Dir: dir, Overlay: filepath.Join(workDir, "overlay.json")}
+	modRoot, ok := findModuleRoot(dir)
+	switch {
+	case !ok:
+		// A loose directory gets a go.mod of its own, in the overlay
+		// only, on the recorder module's ebiten version
+		modRoot = dir
+		gomod := filepath.Join(workDir, "go.mod")
+		data := "module recording-game\n\ngo " + goVersion + "\n"
+		if v := requiredVersion(filepath.Join(root, "go.mod"), ebitenPath); v != "" {
+			data += "\nrequire " + ebitenPath + " " + v + "\n"
+		}
+		if err := os.WriteFile(gomod, []byte(data), 0644); err != nil {
+			return nil, err
+		}
+		replace[filepath.Join(dir, "go.mod")] = gomod
+	case modRoot == root:
+		// Part of the recorder's module; the overlay is all it takes
+		return in, writeOverlay(in.Overlay, replace)
+	}
+
+	in.Work = filepath.Join(workDir, "go.work")
+	work := fmt.Sprintf("go %s\n\nuse (\n\t%s\n\t%s\n)\n", goVersion, quotePath(modRoot), quotePath(root))
+	if err := os.WriteFile(in.Work, []byte(work), 0644); err != nil {
+		return nil, err
+	}
+	return in, writeOverlay(in.Overlay, replace)
+}
+
+// Command returns "go <verb> -overlay ... <args>" set up to run in the
+// game's directory, e.g. in.Command(ctx, "run", ".")
+func (in *Injection) Command(ctx context.Context, verb string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "go", append([]string{verb, "-overlay", in.Overlay}, args...)...)
+	cmd.Dir = in.Dir
+	cmd.Env = in.Env()
+	return cmd
+}
+
+// Env returns the environment for go commands run on the injection
+// Workspaces only allow -mod=readonly, so a -mod flag in GOFLAGS is dropped
+func (in *Injection) Env() []string {
+	if in.Work == "" {
+		return os.Environ()
+	}
+	var env []string
+	for _, kv := range os.Environ() {
+		switch {
+		case strings.HasPrefix(kv, "GOWORK="):
+			continue
+		case strings.HasPrefix(kv, "GOFLAGS="):
+			var flags []string
+			for _, f := range strings.Fields(strings.TrimPrefix(kv, "GOFLAGS=")) {
+				if !strings.HasPrefix(f, "-mod=") {
+					flags = append(flags, f)
+				}
+			}
+			kv = "GOFLAGS=" + strings.Join(flags, " ")
+		}
+		env = append(env, kv)
+	}
+	return append(env, "GOWORK="+in.Work)
+}
+
+// writeOverlay writes the go build -overlay file mapping originals to
+// their replacements
+func writeOverlay(path string, replace map[string]string) error {
+	data, err := json.MarshalIndent(struct{ Replace map[string]string }{replace}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// findModuleRoot returns the nearest directory at or above dir with a go.mod
+func findModuleRoot(dir string) (string, bool) {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// requiredVersion returns the version of module a go.mod requires, or ""
+func requiredVersion(gomod, module string) string {
+	data, err := os.ReadFile(gomod)
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && fields[0] == "require" {
+			fields = fields[1:]
+		}
+		if len(fields) >= 2 && fields[0] == module {
+			return fields[1]
+		}
+	}
+	return ""
+}
+
+// quotePath quotes a go.work path if it needs it
+func quotePath(path string) string {
+	if strings.ContainsAny(path, " \t\"'`") {
+		return fmt.Sprintf("%q", path)
+	}
+	return path
+}
@@ -0,0 +1,447 @@
+// Package gamepatch makes ebiten games record themselves by wrapping
+// their ebiten.RunGame and ebiten.RunGameWithOptions calls in
+// recorder.WrapGame, and the calls themselves in recorder.CloseAll, so
+// input held by an input script is released however the game ends
+//
+// Files are parsed with go/ast, so comments, string literals and aliased
+// or dot imports of ebiten are handled, and the result is printed
+// gofmt-style. The patched sources can be written in place, or kept out
+// of the game's tree with Inject, which builds it through an overlay
+package gamepatch
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const ebitenPath = "github.com/hajimehoshi/ebiten/v2"
+
+// Options configures the WrapGame call patched in
+type Options struct {
+	// Output is the recording path passed to WrapGame
+	Output string
+
+	// Quality is the JPEG quality (1-100)
+	Quality int
+
+	// Duration is the auto-record duration (0 = manual)
+	Duration time.Duration
+
+	// RecorderPath is the import path of the recorder package
+	RecorderPath string
+}
+
+// Patcher rewrites RunGame calls in Go source files
+// Wrapped and Skipped collect a line per call found, for reporting
+type Patcher struct {
+	opts    Options
+	fset    *token.FileSet
+	Wrapped []string
+	Skipped []string // calls that were found but left alone, and why
+}
+
+// New creates a patcher that wraps games as opts says
+func New(opts Options) *Patcher {
+	if opts.Quality <= 0 {
+		opts.Quality = 85
+	}
+	return &Patcher{opts: opts, fset: token.NewFileSet()}
+}
+
+// PatchFile returns the source of path with its RunGame calls wrapped,
+// and how many were; src is nil if there were none
+func (p *Patcher) PatchFile(path string) (src []byte, n int, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	f, err := parser.ParseFile(p.fset, path, data, parser.ParseComments)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ebitenName, ok := importName(f, ebitenPath)
+	if !ok {
+		return nil, 0, nil
+	}
+
+	var calls []*ast.CallExpr
+	ast.Inspect(f, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || !isRunGame(call, ebitenName) {
+			return true
+		}
+		pos := p.fset.Position(call.Pos())
+		switch {
+		case len(call.Args) == 0:
+			p.Skipped = append(p.Skipped, fmt.Sprintf("%s: skipped, RunGame has no arguments", pos))
+		case p.isWrapped(f, call.Args[0]):
+			p.Skipped = append(p.Skipped, fmt.Sprintf("%s: skipped, already wrapped in WrapGame", pos))
+		default:
+			calls = append(calls, call)
+		}
+		return true
+	})
+	if len(calls) == 0 {
+		return nil, 0, nil
+	}
+
+	recorderName := p.ensureImport(f, p.opts.RecorderPath, "recorder")
+	timeName := p.ensureImport(f, "time", "time")
+	for _, call := range calls {
+		p.Wrapped = append(p.Wrapped, fmt.Sprintf("%s: wrapped %s", p.fset.Position(call.Pos()), callName(call)))
+		call.Args[0] = p.wrap(call.Args[0], recorderName, timeName)
+		closeAll(call, recorderName)
+	}
+
+	// Printed the way gofmt does
+	var buf bytes.Buffer
+	cfg := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+	if err := cfg.Fprint(&buf, p.fset, f); err != nil {
+		return nil, 0, err
+	}
+	return buf.Bytes(), len(calls), nil
+}
+
+// GoFiles expands directories to their non-test .go files
+func GoFiles(args []string) ([]string, error) {
+	var files []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(arg, "*.go"))
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			if !strings.HasSuffix(m, "_test.go") {
+				files = append(files, m)
+			}
+		}
+	}
+	if len(files) == 0 {
+		return nil, errors.New("gamepatch: no .go files found")
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// importName returns the name path is imported under in f
+// Dot imports return "."; blank imports count as not imported
+func importName(f *ast.File, path string) (string, bool) {
+	for _, spec := range f.Imports {
+		if importPath(spec) != path {
+			continue
+		}
+		if spec.Name == nil {
+			return packageName(path), true
+		}
+		if spec.Name.Name != "_" {
+			return spec.Name.Name, true
+		}
+	}
+	return "", false
+}
+
+// packageName guesses the name of the package at path, skipping a major
+// version suffix such as /v2
+func packageName(path string) string {
+	dir, base := filepath.Split(path)
+	if len(base) > 1 && base[0] == 'v' && strings.Trim(base[1:], "0123456789") == "" && dir != "" {
+		return filepath.Base(dir)
+	}
+	return base
+}
+
+// isRunGame reports whether call is RunGame or RunGameWithOptions of
+// the ebiten package imported as name
+func isRunGame(call *ast.CallExpr, name string) bool {
+	var fn *ast.Ident
+	switch fun := call.Fun.(type) {
+	case *ast.SelectorExpr:
+		pkg, ok := fun.X.(*ast.Ident)
+		// A package name has no object; a local variable shadowing it does
+		if !ok || pkg.Name != name || pkg.Obj != nil {
+			return false
+		}
+		fn = fun.Sel
+	case *ast.Ident:
+		if name != "." || fun.Obj != nil {
+			return false
+		}
+		fn = fun
+	default:
+		return false
+	}
+	return fn.Name == "RunGame" || fn.Name == "RunGameWithOptions"
+}
+
+func callName(call *ast.CallExpr) string {
+	if sel, ok := call.Fun.(*ast.SelectorExpr); ok {
+		return sel.Sel.Name
+	}
+	return call.Fun.(*ast.Ident).Name
+}
+
+// isWrapped reports whether expr is already a WrapGame call
+func (p *Patcher) isWrapped(f *ast.File, expr ast.Expr) bool {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "WrapGame" {
+		return false
+	}
+	name, ok := importName(f, p.opts.RecorderPath)
+	pkg, isIdent := sel.X.(*ast.Ident)
+	return ok && isIdent && pkg.Name == name
+}
+
+// ensureImport returns the name path is imported under in f, adding the
+// import if needed; it's aliased when name is already taken in the file
+func (p *Patcher) ensureImport(f *ast.File, path, name string) string {
+	if existing, ok := importName(f, path); ok && existing != "." {
+		return existing
+	}
+	alias := ""
+	for used := identNames(f); used[name]; {
+		name += "pkg"
+		alias = name
+	}
+
+	spec := &ast.ImportSpec{Path: &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(path)}}
+	if alias != "" {
+		spec.Name = ast.NewIdent(alias)
+	}
+	f.Imports = append(f.Imports, spec)
+
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		// A single unparenthesized import becomes a block
+		if !gen.Lparen.IsValid() {
+			gen.Lparen = gen.Pos()
+			gen.Rparen = gen.End()
+		}
+		// The spec goes in sorted order into the first group of standard
+		// library imports, or the last group for others, and borrows its
+		// neighbour's position so the printer keeps it in that group
+		group := p.importGroups(gen.Specs)
+		if len(group) == 0 {
+			gen.Specs = append(gen.Specs, spec)
+			return name
+		}
+		g := len(group) - 1
+		if isStd(path) {
+			for i, specs := range group {
+				if !slices.ContainsFunc(specs, notStd) {
+					g = i
+					break
+				}
+			}
+		}
+		at := slices.Index(gen.Specs, ast.Spec(group[g][len(group[g])-1])) + 1
+		spec.Path.ValuePos = group[g][len(group[g])-1].Path.ValuePos
+		for _, s := range group[g] {
+			if importPath(s) > path {
+				at = slices.Index(gen.Specs, ast.Spec(s))
+				spec.Path.ValuePos = s.Path.ValuePos
+				break
+			}
+		}
+		gen.Specs = slices.Insert(gen.Specs, at, ast.Spec(spec))
+		return name
+	}
+
+	gen := &ast.GenDecl{Tok: token.IMPORT, Specs: []ast.Spec{spec}}
+	f.Decls = append([]ast.Decl{gen}, f.Decls...)
+	return name
+}
+
+// importGroups splits specs into the groups a blank line separates
+func (p *Patcher) importGroups(specs []ast.Spec) [][]*ast.ImportSpec {
+	var groups [][]*ast.ImportSpec
+	last := 0
+	for _, s := range specs {
+		spec := s.(*ast.ImportSpec)
+		start := spec.Pos()
+		if spec.Doc != nil {
+			start = spec.Doc.Pos()
+		}
+		if len(groups) == 0 || p.fset.Position(start).Line > last+1 {
+			groups = append(groups, nil)
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], spec)
+		last = p.fset.Position(spec.Path.ValuePos).Line
+	}
+	return groups
+}
+
+// importPath returns the unquoted path of spec
+func importPath(spec *ast.ImportSpec) string {
+	path, _ := strconv.Unquote(spec.Path.Value)
+	return path
+}
+
+// isStd reports whether path is grouped with the standard library, as
+// gofmt and goimports do for any path whose first element has no dot,
+// this module's own main/... packages included
+func isStd(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
+
+// notStd reports whether spec imports a path outside the standard
+// library group
+func notStd(spec *ast.ImportSpec) bool {
+	return !isStd(importPath(spec))
+}
+
+// identNames returns the names declared in or referenced by f, so an
+// added import doesn't collide with a variable or function of the same
+// name; struct fields and selectors can't collide and are left out
+func identNames(f *ast.File) map[string]bool {
+	names := map[string]bool{}
+	fields := map[*ast.Ident]bool{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.StructType:
+			for _, field := range n.Fields.List {
+				for _, id := range field.Names {
+					fields[id] = true
+				}
+			}
+		case *ast.Ident:
+			if n.Obj != nil && !fields[n] {
+				names[n.Name] = true
+			}
+		}
+		return true
+	})
+	for _, id := range f.Unresolved {
+		names[id.Name] = true
+	}
+	return names
+}
+
+// closeAll turns call into recorder.CloseAll(call) in place
+func closeAll(call *ast.CallExpr, recorderName string) {
+	inner := *call
+	*call = ast.CallExpr{
+		Fun: &ast.SelectorExpr{
+			X:   &ast.Ident{NamePos: inner.Pos(), Name: recorderName},
+			Sel: &ast.Ident{NamePos: inner.Pos(), Name: "CloseAll"},
+		},
+		Lparen: inner.Pos(),
+		Args:   []ast.Expr{&inner},
+		Rparen: inner.End(),
+	}
+}
+
+// wrap returns game wrapped in a recorder.WrapGame call
+// The added arguments are placed at game's end, so a comment after the
+// call isn't printed inside it
+func (p *Patcher) wrap(game ast.Expr, recorderName, timeName string) ast.Expr {
+	end := game.End()
+	return &ast.CallExpr{
+		Fun: &ast.SelectorExpr{X: ast.NewIdent(recorderName), Sel: ast.NewIdent("WrapGame")},
+		Args: []ast.Expr{
+			game,
+			&ast.BasicLit{ValuePos: end, Kind: token.STRING, Value: strconv.Quote(p.opts.Output)},
+			&ast.BasicLit{ValuePos: end, Kind: token.INT, Value: strconv.Itoa(p.opts.Quality)},
+			&ast.Ident{NamePos: end, Name: "true"},
+			durationExpr(p.opts.Duration, timeName, end),
+		},
+		Rparen: end,
+	}
+}
+
+// durationExpr returns d as n*time.Unit with the largest exact unit, or 0,
+// placed at pos
+func durationExpr(d time.Duration, timeName string, pos token.Pos) ast.Expr {
+	units := []struct {
+		name string
+		d    time.Duration
+	}{
+		{"Hour", time.Hour},
+		{"Minute", time.Minute},
+		{"Second", time.Second},
+		{"Millisecond", time.Millisecond},
+		{"Microsecond", time.Microsecond},
+		{"Nanosecond", time.Nanosecond},
+	}
+	if d == 0 {
+		return &ast.BasicLit{ValuePos: pos, Kind: token.INT, Value: "0"}
+	}
+	unit := units[len(units)-1]
+	for _, u := range units {
+		if d%u.d == 0 {
+			unit = u
+			break
+		}
+	}
+	return &ast.BinaryExpr{
+		X:     &ast.BasicLit{ValuePos: pos, Kind: token.INT, Value: strconv.FormatInt(int64(d/unit.d), 10)},
+		OpPos: pos,
+		Op:    token.MUL,
+		Y:     &ast.SelectorExpr{X: &ast.Ident{NamePos: pos, Name: timeName}, Sel: &ast.Ident{NamePos: pos, Name: unit.name}},
+	}
+}
+
+// ModulePath returns the module path declared in dir's go.mod
+func ModulePath(dir string) (string, error) {
+	module, _, err := readGoMod(filepath.Join(dir, "go.mod"))
+	return module, err
+}
+
+// readGoMod returns the module path and go version of a go.mod
+func readGoMod(path string) (module, goVersion string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		switch fields[0] {
+		case "module":
+			module = strings.Trim(fields[1], `"`)
+		case "go":
+			goVersion = fields[1]
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return "", "", err
+	}
+	if module == "" || goVersion == "" {
+		return "", "", fmt.Errorf("gamepatch: %s: no module or go directive", path)
+	}
+	return module, goVersion, nil
+}
@@ -0,0 +1,366 @@
+package gamepatch
+
+import (
+	"go/format"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRecorder = "github.com/joeblew999/ebiten-test/pkg/recorder"
+
+// patch patches src as a file of its own
+func patch(t *testing.T, opts Options, src string) (*Patcher, string, int) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "main.go")
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := New(opts)
+	out, n, err := p.PatchFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, string(out), n
+}
+
+func TestPatchFile(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		n    int
+		want []string // in the patched source
+		not  []string // not in the patched source
+	}{
+		{
+			name: "RunGame",
+			src: `package main
+
+import "github.com/hajimehoshi/ebiten/v2"
+
+func main() {
+	ebiten.RunGame(&Game{})
+}
+`,
+			n: 1,
+			want: []string{
+				`recorder.CloseAll(ebiten.RunGame(recorder.WrapGame(&Game{}, "rec.avi", 85, true, 10*time.Second)))`,
+				"\t\"" + testRecorder + "\"\n",
+				"\t\"time\"\n",
+			},
+		},
+		{
+			name: "RunGameWithOptions",
+			src: `package main
+
+import "github.com/hajimehoshi/ebiten/v2"
+
+func main() {
+	ebiten.RunGameWithOptions(&Game{}, &ebiten.RunGameOptions{})
+}
+`,
+			n:    1,
+			want: []string{`recorder.CloseAll(ebiten.RunGameWithOptions(recorder.WrapGame(&Game{}, "rec.avi", 85, true, 10*time.Second), &ebiten.RunGameOptions{}))`},
+		},
+		{
+			name: "RunGame in an if statement",
+			src: `package main
+
+import (
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+func main() {
+	if err := ebiten.RunGame(&Game{}); err != nil {
+		log.Fatal(err)
+	}
+}
+`,
+			n:    1,
+			want: []string{`if err := recorder.CloseAll(ebiten.RunGame(recorder.WrapGame(&Game{}, "rec.avi", 85, true, 10*time.Second))); err != nil {`},
+		},
+		{
+			name: "aliased import",
+			src: `package main
+
+import eb "github.com/hajimehoshi/ebiten/v2"
+
+func main() {
+	eb.RunGame(&Game{})
+}
+`,
+			n:    1,
+			want: []string{`recorder.CloseAll(eb.RunGame(recorder.WrapGame(&Game{}, "rec.avi", 85, true, 10*time.Second)))`},
+		},
+		{
+			name: "dot import",
+			src: `package main
+
+import . "github.com/hajimehoshi/ebiten/v2"
+
+func main() {
+	RunGame(&Game{})
+}
+`,
+			n:    1,
+			want: []string{`recorder.CloseAll(RunGame(recorder.WrapGame(&Game{}, "rec.avi", 85, true, 10*time.Second)))`},
+		},
+		{
+			name: "shadowed ebiten",
+			src: `package main
+
+import "github.com/hajimehoshi/ebiten/v2"
+
+type runner struct{}
+
+func (runner) RunGame(g ebiten.Game) error { return nil }
+
+func main() {
+	ebiten := runner{}
+	ebiten.RunGame(&Game{})
+}
+`,
+			n: 0,
+		},
+		{
+			name: "comments and strings",
+			src: `package main
+
+import (
+	"fmt"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// main calls ebiten.RunGame(&Game{})
+func main() {
+	fmt.Println("ebiten.RunGame(&Game{})")
+	ebiten.RunGame(&Game{}) // ebiten.RunGame(g)
+}
+`,
+			n: 1,
+			want: []string{
+				"// main calls ebiten.RunGame(&Game{})\n",
+				`fmt.Println("ebiten.RunGame(&Game{})")`,
+				`recorder.CloseAll(ebiten.RunGame(recorder.WrapGame(&Game{}, "rec.avi", 85, true, 10*time.Second))) // ebiten.RunGame(g)`,
+			},
+			not: []string{`recorder.WrapGame(&Game{}, "rec.avi", 85, true, 10*time.Second))")`},
+		},
+		{
+			name: "time and recorder names taken",
+			src: `package main
+
+import (
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+var recorder = time.Now()
+
+func main() {
+	ebiten.RunGame(&Game{})
+}
+`,
+			n: 1,
+			want: []string{
+				"\trecorderpkg \"" + testRecorder + "\"\n",
+				`recorderpkg.CloseAll(ebiten.RunGame(recorderpkg.WrapGame(&Game{}, "rec.avi", 85, true, 10*time.Second)))`,
+			},
+			not: []string{"timepkg"},
+		},
+		{
+			name: "no ebiten import",
+			src: `package main
+
+func main() {
+	RunGame(&Game{})
+}
+`,
+			n: 0,
+		},
+		{
+			name: "no RunGame call",
+			src: `package main
+
+import "github.com/hajimehoshi/ebiten/v2"
+
+func main() {
+	ebiten.SetWindowTitle("RunGame")
+}
+`,
+			n: 0,
+		},
+	}
+	opts := Options{Output: "rec.avi", Duration: 10 * time.Second, RecorderPath: testRecorder}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, out, n := patch(t, opts, tt.src)
+			if n != tt.n {
+				t.Fatalf("patched %d call(s), want %d\n%s", n, tt.n, out)
+			}
+			if n == 0 {
+				if out != "" {
+					t.Errorf("got source with nothing patched:\n%s", out)
+				}
+				return
+			}
+			formatted, err := format.Source([]byte(out))
+			if err != nil {
+				t.Fatalf("patched source doesn't parse: %v\n%s", err, out)
+			}
+			if string(formatted) != out {
+				t.Errorf("patched source isn't gofmt'd:\n%s", out)
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(out, s) {
+					t.Errorf("missing %q in:\n%s", s, out)
+				}
+			}
+			for _, s := range tt.not {
+				if strings.Contains(out, s) {
+					t.Errorf("unexpected %q in:\n%s", s, out)
+				}
+			}
+		})
+	}
+}
+
+func TestPatchFileImportGroups(t *testing.T) {
+	tests := []struct {
+		name     string
+		recorder string
+		imports  string
+		want     string
+	}{
+		{
+			name:     "module recorder",
+			recorder: "main/pkg/recorder",
+			imports: `import (
+	"fmt"
+	"os"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)`,
+			want: `import (
+	"fmt"
+	"main/pkg/recorder"
+	"os"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)`,
+		},
+		{
+			name:     "third-party recorder",
+			recorder: testRecorder,
+			imports: `import (
+	"fmt"
+	"os"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)`,
+			want: `import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"` + testRecorder + `"
+)`,
+		},
+		{
+			name:     "documented import",
+			recorder: "main/pkg/recorder",
+			imports: `import (
+	"fmt"
+
+	// ebiten draws the game
+	"github.com/hajimehoshi/ebiten/v2"
+)`,
+			want: `import (
+	"fmt"
+	"main/pkg/recorder"
+	"time"
+
+	// ebiten draws the game
+	"github.com/hajimehoshi/ebiten/v2"
+)`,
+		},
+		{
+			name:     "no standard library group",
+			recorder: testRecorder,
+			imports:  `import "github.com/hajimehoshi/ebiten/v2"`,
+			want: `import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"` + testRecorder + `"
+	"time"
+)`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "package main\n\n" + tt.imports + "\n\nfunc main() {\n\tfmt.Println(os.Args)\n\tebiten.RunGame(&Game{})\n}\n"
+			_, out, n := patch(t, Options{Output: "rec.avi", Duration: time.Second, RecorderPath: tt.recorder}, src)
+			if n != 1 {
+				t.Fatalf("patched %d call(s), want 1", n)
+			}
+			if !strings.Contains(out, tt.want) {
+				t.Errorf("imports aren't\n%s\nin:\n%s", tt.want, out)
+			}
+			if formatted, err := format.Source([]byte(out)); err != nil || string(formatted) != out {
+				t.Errorf("patched source isn't gofmt'd (%v):\n%s", err, out)
+			}
+		})
+	}
+}
+
+func TestPatchFileSkipsWrapped(t *testing.T) {
+	src := `package main
+
+import (
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/joeblew999/ebiten-test/pkg/recorder"
+)
+
+func main() {
+	recorder.CloseAll(ebiten.RunGame(recorder.WrapGame(&Game{}, "rec.avi", 85, true, time.Second)))
+}
+`
+	p, out, n := patch(t, Options{RecorderPath: testRecorder}, src)
+	if n != 0 || out != "" {
+		t.Errorf("patched %d call(s), want none", n)
+	}
+	if len(p.Skipped) != 1 || !strings.Contains(p.Skipped[0], "already wrapped") {
+		t.Errorf("Skipped = %q, want the wrapped call", p.Skipped)
+	}
+}
+
+func TestDurationExpr(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0"},
+		{10 * time.Second, "10 * time.Second"},
+		{90 * time.Second, "90 * time.Second"},
+		{2 * time.Minute, "2 * time.Minute"},
+		{1500 * time.Millisecond, "1500 * time.Millisecond"},
+	}
+	for _, tt := range tests {
+		var buf strings.Builder
+		if err := format.Node(&buf, token.NewFileSet(), durationExpr(tt.d, "time", token.NoPos)); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("durationExpr(%v) = %s, want %s", tt.d, buf.String(), tt.want)
+		}
+	}
+}
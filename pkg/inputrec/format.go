@@ -0,0 +1,138 @@
+package inputrec
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	input "github.com/quasilyte/ebitengine-input"
+)
+
+// File layout (all integers are unsigned varints unless noted):
+//
+//	magic "EIREC1"
+//	action count, then each recorded input.Action
+//	per tick: entry count, then per entry:
+//	    handler index, action index, flags byte,
+//	    if flagHasPos: pos x, pos y, start x, start y as little-endian float32
+//
+// Ticks where nothing is pressed cost a single byte
+
+const magic = "EIREC1"
+
+// Just-pressed edges aren't logged: ebitengine-input can't simulate a
+// release, so an action released and pressed again between two ticks
+// replays as held either way. Bit 1 was once used for them and is
+// ignored on replay
+const (
+	flagPressed byte = 1 << 0
+	flagHasPos  byte = 1 << 2
+)
+
+// entry is the state of one action of one handler during one tick
+type entry struct {
+	handler  int
+	action   int // index into the recorded action list
+	flags    byte
+	pos      input.Vec
+	startPos input.Vec
+}
+
+func writeHeader(w *bufio.Writer, actions []input.Action) error {
+	buf := []byte(magic)
+	buf = binary.AppendUvarint(buf, uint64(len(actions)))
+	for _, a := range actions {
+		buf = binary.AppendUvarint(buf, uint64(a))
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+func readHeader(r *bufio.Reader) ([]input.Action, error) {
+	head := make([]byte, len(magic))
+	if _, err := io.ReadFull(r, head); err != nil {
+		return nil, err
+	}
+	if string(head) != magic {
+		return nil, errors.New("inputrec: not an input recording")
+	}
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	actions := make([]input.Action, n)
+	for i := range actions {
+		a, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		actions[i] = input.Action(a)
+	}
+	return actions, nil
+}
+
+func appendTick(buf []byte, entries []entry) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(entries)))
+	for _, e := range entries {
+		buf = binary.AppendUvarint(buf, uint64(e.handler))
+		buf = binary.AppendUvarint(buf, uint64(e.action))
+		buf = append(buf, e.flags)
+		if e.flags&flagHasPos != 0 {
+			for _, v := range [4]float64{e.pos.X, e.pos.Y, e.startPos.X, e.startPos.Y} {
+				buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(float32(v)))
+			}
+		}
+	}
+	return buf
+}
+
+// readTick reads the next tick into entries
+// It returns io.EOF once the recording is exhausted
+func readTick(r *bufio.Reader, entries []entry, numActions int) ([]entry, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return entries, err
+	}
+	entries = entries[:0]
+	for i := uint64(0); i < n; i++ {
+		var e entry
+		h, err := binary.ReadUvarint(r)
+		if err != nil {
+			return entries, unexpectedEOF(err)
+		}
+		a, err := binary.ReadUvarint(r)
+		if err != nil {
+			return entries, unexpectedEOF(err)
+		}
+		if a >= uint64(numActions) {
+			return entries, fmt.Errorf("inputrec: action index %d out of range", a)
+		}
+		e.handler, e.action = int(h), int(a)
+		if e.flags, err = r.ReadByte(); err != nil {
+			return entries, unexpectedEOF(err)
+		}
+		if e.flags&flagHasPos != 0 {
+			var raw [16]byte
+			if _, err := io.ReadFull(r, raw[:]); err != nil {
+				return entries, unexpectedEOF(err)
+			}
+			f := func(i int) float64 {
+				return float64(math.Float32frombits(binary.LittleEndian.Uint32(raw[i*4:])))
+			}
+			e.pos = input.Vec{X: f(0), Y: f(1)}
+			e.startPos = input.Vec{X: f(2), Y: f(3)}
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
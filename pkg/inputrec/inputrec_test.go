@@ -0,0 +1,261 @@
+package inputrec
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	input "github.com/quasilyte/ebitengine-input"
+)
+
+const (
+	actionJump input.Action = iota + 1
+	actionFire
+	actionClick
+)
+
+var testActions = []input.Action{actionJump, actionFire, actionClick}
+
+// testTicks spans two handlers, positions and an empty tick
+var testTicks = [][]entry{
+	{{handler: 0, action: 0, flags: flagPressed}},
+	{},
+	{
+		{handler: 0, action: 0, flags: flagPressed},
+		{handler: 1, action: 1, flags: flagPressed},
+		{handler: 1, action: 2, flags: flagPressed | flagHasPos,
+			pos: input.Vec{X: 120.5, Y: 64}, startPos: input.Vec{X: 100, Y: 60.25}},
+	},
+}
+
+// encode returns a log of ticks
+func encode(t *testing.T, actions []input.Action, ticks [][]entry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	if err := writeHeader(w, actions); err != nil {
+		t.Fatal(err)
+	}
+	for _, tick := range ticks {
+		if _, err := w.Write(appendTick(nil, tick)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	r := bufio.NewReader(bytes.NewReader(encode(t, testActions, testTicks)))
+	actions, err := readHeader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actions, testActions) {
+		t.Errorf("actions = %v, want %v", actions, testActions)
+	}
+	var entries []entry
+	for i, want := range testTicks {
+		if entries, err = readTick(r, entries, len(actions)); err != nil {
+			t.Fatalf("tick %d: %v", i, err)
+		}
+		if len(entries) != len(want) || (len(want) > 0 && !reflect.DeepEqual(entries, want)) {
+			t.Errorf("tick %d = %+v, want %+v", i, entries, want)
+		}
+	}
+	if _, err := readTick(r, entries, len(actions)); err != io.EOF {
+		t.Errorf("after the last tick: %v, want io.EOF", err)
+	}
+}
+
+func TestTruncatedTick(t *testing.T) {
+	data := encode(t, testActions, testTicks)
+	// Cut inside the last entry's positions
+	r := bufio.NewReader(bytes.NewReader(data[:len(data)-3]))
+	if _, err := readHeader(r); err != nil {
+		t.Fatal(err)
+	}
+	var err error
+	var entries []entry
+	for range testTicks {
+		if entries, err = readTick(r, entries, len(testActions)); err != nil {
+			break
+		}
+	}
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("err = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestActionOutOfRange(t *testing.T) {
+	data := encode(t, testActions[:1], [][]entry{{{handler: 0, action: 2, flags: flagPressed}}})
+	p, err := NewPlayer(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sys input.System
+	sys.Init(input.SystemConfig{})
+	h := sys.NewHandler(0, input.Keymap{actionJump: {input.KeyA}})
+	if p.Emit([]*input.Handler{h}) {
+		t.Fatal("Emit succeeded with an action index past the recorded actions")
+	}
+	if p.Err() == nil || !p.Done() {
+		t.Errorf("Err() = %v, Done() = %v; want an error and done", p.Err(), p.Done())
+	}
+}
+
+func TestNotARecording(t *testing.T) {
+	if _, err := NewPlayer(bytes.NewReader([]byte("EIREC0\x00"))); err == nil {
+		t.Error("NewPlayer accepted a bad magic")
+	}
+}
+
+// keymaps gives each player its own keys, so simulated key events,
+// which any handler can see, reach one player only
+var keymaps = []input.Keymap{
+	{actionJump: {input.KeySpace}, actionFire: {input.KeyX}, actionClick: {input.KeyMouseLeft}},
+	{actionJump: {input.KeyUp}, actionFire: {input.KeyEnter}, actionClick: {input.KeyMouseRight}},
+}
+
+// newHandlers returns a system with a handler per keymap
+func newHandlers() (*input.System, []*input.Handler) {
+	sys := &input.System{}
+	sys.Init(input.SystemConfig{})
+	handlers := make([]*input.Handler, len(keymaps))
+	for i, keymap := range keymaps {
+		handlers[i] = sys.NewHandler(uint8(i), keymap)
+	}
+	return sys, handlers
+}
+
+// state is what a game sees of one handler's action during a tick
+type state struct {
+	pressed     bool
+	justPressed bool
+	pos         input.Vec // where it was just pressed
+}
+
+func snapshot(handlers []*input.Handler) []state {
+	var states []state
+	for _, h := range handlers {
+		for _, a := range testActions {
+			s := state{pressed: h.ActionIsPressed(a), justPressed: h.ActionIsJustPressed(a)}
+			if info, ok := h.JustPressedActionInfo(a); ok && info.HasPos() {
+				s.pos = info.Pos
+			}
+			states = append(states, s)
+		}
+	}
+	return states
+}
+
+func TestRecordReplay(t *testing.T) {
+	// Each tick's keys, standing in for the live devices
+	type press struct {
+		handler int
+		key     input.Key
+		pos     input.Vec
+	}
+	script := [][]press{
+		{{0, input.KeySpace, input.Vec{}}},
+		{{0, input.KeySpace, input.Vec{}}, {1, input.KeyEnter, input.Vec{}}},
+		{},
+		{{1, input.KeyMouseRight, input.Vec{X: 32, Y: 48}}},
+		{{0, input.KeyX, input.Vec{}}, {1, input.KeyMouseRight, input.Vec{X: 33, Y: 50}}},
+	}
+
+	sys, handlers := newHandlers()
+	var log bytes.Buffer
+	rec, err := NewRecorder(&log, testActions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want [][]state
+	for _, tick := range script {
+		for _, p := range tick {
+			handlers[p.handler].EmitKeyEvent(input.SimulatedKeyEvent{Key: p.key, Pos: p.pos})
+		}
+		sys.Update()
+		want = append(want, snapshot(handlers))
+		if err := rec.Record(handlers); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rec.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Ticks() != len(script) {
+		t.Errorf("recorded %d ticks, want %d", rec.Ticks(), len(script))
+	}
+	checkFlags(t, log.Bytes())
+
+	sys, handlers = newHandlers()
+	player, err := NewPlayer(&log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range script {
+		if !player.Emit(handlers) {
+			t.Fatalf("tick %d: replay ended early: %v", i, player.Err())
+		}
+		sys.Update()
+		if got := snapshot(handlers); !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("tick %d: replayed %+v, recorded %+v", i, got, want[i])
+		}
+	}
+	if player.Emit(handlers) || !player.Done() || player.Err() != nil {
+		t.Errorf("after the log: Done() = %v, Err() = %v; want done without an error", player.Done(), player.Err())
+	}
+}
+
+// checkFlags checks that a log has no just-pressed flags, which replay
+// couldn't honor
+func checkFlags(t *testing.T, data []byte) {
+	t.Helper()
+	r := bufio.NewReader(bytes.NewReader(data))
+	actions, err := readHeader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []entry
+	for tick := 0; ; tick++ {
+		if entries, err = readTick(r, entries, len(actions)); err == io.EOF {
+			return
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range entries {
+			if e.flags&^(flagPressed|flagHasPos) != 0 {
+				t.Errorf("tick %d: handler %d action %d has flags %#x", tick, e.handler, e.action, e.flags)
+			}
+		}
+	}
+}
+
+func TestReplayIgnoresJustPressed(t *testing.T) {
+	// Older logs flagged just-pressed entries with bit 1
+	const oldJustPressed = 1 << 1
+	data := encode(t, testActions, [][]entry{
+		{{handler: 0, action: 0, flags: flagPressed | oldJustPressed}},
+		{{handler: 0, action: 0, flags: flagPressed}},
+	})
+	sys, handlers := newHandlers()
+	p, err := NewPlayer(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []state{{pressed: true, justPressed: true}, {pressed: true}} {
+		if !p.Emit(handlers) {
+			t.Fatalf("tick %d: replay ended early: %v", i, p.Err())
+		}
+		sys.Update()
+		if got := snapshot(handlers)[0]; got != want {
+			t.Errorf("tick %d: jump is %+v, want %+v", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package inputrec
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	input "github.com/quasilyte/ebitengine-input"
+)
+
+// Player feeds a recorded input log back into input handlers
+// It uses the ebitengine-input simulated action API, so the game sees
+// the recorded actions (and their positions) through its usual Handler calls
+type Player struct {
+	r       *bufio.Reader
+	actions []input.Action
+	entries []entry
+	ticks   int
+	done    bool
+	err     error
+}
+
+// NewPlayer creates a player reading the log from r
+func NewPlayer(r io.Reader) (*Player, error) {
+	p := &Player{r: bufio.NewReader(r)}
+	actions, err := readHeader(p.r)
+	if err != nil {
+		return nil, err
+	}
+	p.actions = actions
+	return p, nil
+}
+
+// Emit sends the next tick's actions to handlers
+// Call it once per tick, right before input.System.Update, so the
+// events become visible in the same tick they were recorded
+// It returns false once the log is exhausted or unreadable; see Err
+func (p *Player) Emit(handlers []*input.Handler) bool {
+	if p.done {
+		return false
+	}
+
+	entries, err := readTick(p.r, p.entries, len(p.actions))
+	p.entries = entries
+	if err != nil {
+		p.done = true
+		if err != io.EOF {
+			p.err = err
+		}
+		return false
+	}
+
+	for _, e := range p.entries {
+		if e.handler >= len(handlers) {
+			p.done = true
+			p.err = fmt.Errorf("inputrec: tick %d uses handler %d, only %d attached", p.ticks, e.handler, len(handlers))
+			return false
+		}
+		// A simulated action is pressed while it's emitted every tick,
+		// and just pressed on the first tick it appears after a tick
+		// without it
+		handlers[e.handler].EmitEvent(input.SimulatedAction{
+			Action:   p.actions[e.action],
+			Pos:      e.pos,
+			StartPos: e.startPos,
+		})
+	}
+	p.ticks++
+	return true
+}
+
+// Done reports whether the log has been fully replayed
+func (p *Player) Done() bool {
+	return p.done
+}
+
+// Ticks returns the number of ticks replayed so far
+func (p *Player) Ticks() int {
+	return p.ticks
+}
+
+// Err returns the error that stopped the replay early, if any
+func (p *Player) Err() error {
+	return p.err
+}
@@ -0,0 +1,79 @@
+package inputrec
+
+import (
+	"bufio"
+	"io"
+
+	input "github.com/quasilyte/ebitengine-input"
+)
+
+// Recorder logs the per-tick action states of a set of input handlers
+type Recorder struct {
+	w       *bufio.Writer
+	actions []input.Action
+	entries []entry
+	buf     []byte
+	ticks   int
+}
+
+// NewRecorder creates a recorder writing to w
+// actions: the actions to track; anything else is not recorded
+func NewRecorder(w io.Writer, actions []input.Action) (*Recorder, error) {
+	r := &Recorder{
+		w:       bufio.NewWriter(w),
+		actions: append([]input.Action(nil), actions...),
+	}
+	if err := writeHeader(r.w, r.actions); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Record logs the current state of every handler
+// Call it once per tick, right after input.System.Update
+// The handler order must be the same on every call and during replay
+func (r *Recorder) Record(handlers []*input.Handler) error {
+	r.entries = r.entries[:0]
+	for hi, h := range handlers {
+		for ai, a := range r.actions {
+			var e entry
+			// JustPressedActionInfo carries the click/tap position,
+			// PressedActionInfo the current one for held actions
+			if info, ok := h.JustPressedActionInfo(a); ok {
+				e.flags |= flagPressed
+				if info.HasPos() {
+					e.flags |= flagHasPos
+					e.pos, e.startPos = info.Pos, info.StartPos
+				}
+			} else if info, ok := h.PressedActionInfo(a); ok {
+				e.flags |= flagPressed
+				if info.HasPos() {
+					e.flags |= flagHasPos
+					e.pos, e.startPos = info.Pos, info.StartPos
+				}
+			}
+			if e.flags == 0 {
+				continue
+			}
+			e.handler, e.action = hi, ai
+			r.entries = append(r.entries, e)
+		}
+	}
+
+	r.buf = appendTick(r.buf[:0], r.entries)
+	if _, err := r.w.Write(r.buf); err != nil {
+		return err
+	}
+	r.ticks++
+	return nil
+}
+
+// Ticks returns the number of ticks recorded so far
+func (r *Recorder) Ticks() int {
+	return r.ticks
+}
+
+// Flush writes any buffered data to the underlying writer
+func (r *Recorder) Flush() error {
+	return r.w.Flush()
+}
@@ -0,0 +1,117 @@
+package inputrec
+
+import (
+	"errors"
+	"os"
+
+	input "github.com/quasilyte/ebitengine-input"
+)
+
+// System is a drop-in replacement for input.System that can record the
+// action states of its handlers to a file, or replay them from one
+//
+// Store it by value like input.System, call Init once, then optionally
+// RecordTo or ReplayFrom before the first Update
+//
+// During replay the live devices are still read, so don't touch the
+// input while regenerating a video
+type System struct {
+	input.System
+
+	handlers []*input.Handler
+	recorder *Recorder
+	player   *Player
+	file     *os.File
+	err      error
+}
+
+// NewHandler creates a handler like input.System.NewHandler and tracks it
+// Handlers are recorded and replayed in creation order
+func (s *System) NewHandler(playerID uint8, keymap input.Keymap) *input.Handler {
+	h := s.System.NewHandler(playerID, keymap)
+	s.handlers = append(s.handlers, h)
+	return h
+}
+
+// RecordTo starts logging the given actions of every handler to path
+func (s *System) RecordTo(path string, actions ...input.Action) error {
+	if s.file != nil {
+		return errors.New("inputrec: already recording or replaying")
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	r, err := NewRecorder(f, actions)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	s.file, s.recorder = f, r
+	return nil
+}
+
+// ReplayFrom starts feeding the log at path back into the handlers
+// Once the log is exhausted the system goes back to live input only
+func (s *System) ReplayFrom(path string) error {
+	if s.file != nil {
+		return errors.New("inputrec: already recording or replaying")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	p, err := NewPlayer(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	s.file, s.player = f, p
+	return nil
+}
+
+// Update replaces input.System.Update
+// It emits the replayed actions before updating, and records the
+// handler states after updating
+func (s *System) Update() {
+	if s.player != nil && !s.player.Emit(s.handlers) {
+		s.err = s.player.Err()
+		s.Close()
+	}
+
+	s.System.Update()
+
+	if s.recorder != nil {
+		if err := s.recorder.Record(s.handlers); err != nil {
+			s.err = err
+			s.Close()
+		}
+	}
+}
+
+// Replaying reports whether a replay is still in progress
+func (s *System) Replaying() bool {
+	return s.player != nil
+}
+
+// Err returns the error that stopped recording or replay early, if any
+func (s *System) Err() error {
+	return s.err
+}
+
+// Close stops recording or replaying and closes the log file
+// Call it before the game exits so the recording is flushed
+func (s *System) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	var err error
+	if s.recorder != nil {
+		err = s.recorder.Flush()
+	}
+	if cerr := s.file.Close(); err == nil {
+		err = cerr
+	}
+	s.file, s.recorder, s.player = nil, nil, nil
+	return err
+}
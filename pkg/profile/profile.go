@@ -0,0 +1,177 @@
+package profile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"main/pkg/settings"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultPath is where cmd/record and cmd/batch look for profiles,
+// relative to the project directory
+const DefaultPath = "scripts/profiles.json"
+
+// Profile is how to record one game
+// Zero fields are unset: the default profile or the recorder's own
+// defaults apply
+type Profile struct {
+	// Duration is how long to record; "0s" records until the game exits
+	Duration *settings.Duration `json:"duration,omitempty"`
+
+	FPS     int    `json:"fps,omitempty"`
+	Format  string `json:"format,omitempty"`  // avi, gif or webp
+	Quality int    `json:"quality,omitempty"` // JPEG quality for AVI (1-100)
+
+	// Script is the bot input script, relative to the profile file
+	Script string `json:"script,omitempty"`
+
+	// Window is the window size as WxH, e.g. "640x480", or 720p or 1080p
+	Window string `json:"window,omitempty"`
+
+	Args []string          `json:"args,omitempty"` // game command-line args
+	Env  map[string]string `json:"env,omitempty"`  // extra environment for the game
+
+	// Skip excludes the game from recording; Reason says why
+	Skip   bool   `json:"skip,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Profiles is a profile file: a default profile and per-game overrides,
+// keyed by game directory name
+type Profiles struct {
+	Default Profile            `json:"default"`
+	Games   map[string]Profile `json:"games"`
+
+	dir string // the file's directory, for Script
+}
+
+// Load reads and validates a profile file
+// Unknown fields are errors, so a typo doesn't silently do nothing
+func Load(path string) (*Profiles, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var ps Profiles
+	if err := dec.Decode(&ps); err != nil {
+		return nil, fmt.Errorf("profile: %s: %w", path, err)
+	}
+	if err := ps.Validate(); err != nil {
+		return nil, fmt.Errorf("profile: %s: %w", path, err)
+	}
+	if ps.dir, err = filepath.Abs(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	return &ps, nil
+}
+
+// Validate checks the default and every game's profile
+func (ps *Profiles) Validate() error {
+	if err := ps.Default.validate(); err != nil {
+		return fmt.Errorf("default: %w", err)
+	}
+	names := make([]string, 0, len(ps.Games))
+	for name := range ps.Games {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := ps.Games[name].validate(); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (p Profile) validate() error {
+	if p.Duration != nil && *p.Duration < 0 {
+		return errors.New("negative duration")
+	}
+	if p.FPS < 0 {
+		return errors.New("negative fps")
+	}
+	switch strings.ToLower(p.Format) {
+	case "", "avi", "gif", "webp":
+	default:
+		return fmt.Errorf("unknown format %q (want avi, gif or webp)", p.Format)
+	}
+	if p.Quality < 0 || p.Quality > 100 {
+		return fmt.Errorf("quality %d out of range (1-100)", p.Quality)
+	}
+	if p.Window != "" {
+		if _, err := settings.ParseSize(p.Window); err != nil {
+			return fmt.Errorf("window: %w", err)
+		}
+	}
+	if p.Skip && p.Reason == "" {
+		return errors.New("skip needs a reason")
+	}
+	return nil
+}
+
+// For returns game's profile on top of the default one
+// Env is merged, Args replace the default's; a nil Profiles has no
+// profiles
+func (ps *Profiles) For(game string) Profile {
+	if ps == nil {
+		return Profile{}
+	}
+	p := ps.Default
+	g := ps.Games[game]
+	if g.Duration != nil {
+		p.Duration = g.Duration
+	}
+	if g.FPS != 0 {
+		p.FPS = g.FPS
+	}
+	if g.Format != "" {
+		p.Format = g.Format
+	}
+	if g.Quality != 0 {
+		p.Quality = g.Quality
+	}
+	if g.Script != "" {
+		p.Script = g.Script
+	}
+	if g.Window != "" {
+		p.Window = g.Window
+	}
+	if g.Args != nil {
+		p.Args = g.Args
+	}
+	if len(g.Env) > 0 {
+		env := make(map[string]string, len(p.Env)+len(g.Env))
+		for k, v := range p.Env {
+			env[k] = v
+		}
+		for k, v := range g.Env {
+			env[k] = v
+		}
+		p.Env = env
+	}
+	if g.Skip {
+		p.Skip, p.Reason = true, g.Reason
+	}
+	p.Format = strings.ToLower(p.Format)
+	if p.Script != "" && !filepath.IsAbs(p.Script) {
+		p.Script = filepath.Join(ps.dir, p.Script)
+	}
+	return p
+}
+
+// Environ returns Env as sorted KEY=value pairs
+func (p Profile) Environ() []string {
+	env := make([]string, 0, len(p.Env))
+	for k, v := range p.Env {
+		env = append(env, k+"="+v)
+	}
+	sort.Strings(env)
+	return env
+}
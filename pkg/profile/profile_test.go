@@ -0,0 +1,123 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// load writes data as a profile file and loads it
+func load(t *testing.T, data string) (*Profiles, string, error) {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "profiles.json")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ps, err := Load(path)
+	return ps, dir, err
+}
+
+const testProfiles = `{
+  "default": {
+    "duration": "10s",
+    "format": "avi",
+    "args": ["-fullscreen"],
+    "env": {"EBITEN_GRAPHICS_LIBRARY": "opengl", "SEED": "1"}
+  },
+  "games": {
+    "flappy": {
+      "duration": "20s",
+      "format": "WEBP",
+      "script": "bots/flappy.json",
+      "args": [],
+      "env": {"SEED": "42"}
+    },
+    "blocks": {
+      "window": "640x480",
+      "script": "/abs/blocks.json"
+    },
+    "gamepad": {"skip": true, "reason": "needs a gamepad"}
+  }
+}`
+
+func TestFor(t *testing.T) {
+	ps, dir, err := load(t, testProfiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flappy := ps.For("flappy")
+	if flappy.Duration == nil || time.Duration(*flappy.Duration) != 20*time.Second {
+		t.Errorf("flappy duration = %v, want 20s", flappy.Duration)
+	}
+	if flappy.Format != "webp" {
+		t.Errorf("flappy format = %q, want webp", flappy.Format)
+	}
+	// Env is merged, with the game's value winning
+	if want := map[string]string{"EBITEN_GRAPHICS_LIBRARY": "opengl", "SEED": "42"}; !reflect.DeepEqual(flappy.Env, want) {
+		t.Errorf("flappy env = %v, want %v", flappy.Env, want)
+	}
+	if env := flappy.Environ(); !reflect.DeepEqual(env, []string{"EBITEN_GRAPHICS_LIBRARY=opengl", "SEED=42"}) {
+		t.Errorf("flappy Environ() = %q", env)
+	}
+	// Args replace the default's, even with an empty list
+	if flappy.Args == nil || len(flappy.Args) != 0 {
+		t.Errorf("flappy args = %q, want none", flappy.Args)
+	}
+	// Script is relative to the profile file
+	if want := filepath.Join(dir, "bots", "flappy.json"); flappy.Script != want {
+		t.Errorf("flappy script = %q, want %q", flappy.Script, want)
+	}
+	if ps.Default.Env["SEED"] != "1" {
+		t.Error("For changed the default profile's env")
+	}
+
+	blocks := ps.For("blocks")
+	if blocks.Window != "640x480" || time.Duration(*blocks.Duration) != 10*time.Second {
+		t.Errorf("blocks window %q, duration %v; want 640x480 and the default 10s", blocks.Window, blocks.Duration)
+	}
+	if !reflect.DeepEqual(blocks.Args, []string{"-fullscreen"}) {
+		t.Errorf("blocks args = %q, want the default's", blocks.Args)
+	}
+	if blocks.Script != "/abs/blocks.json" {
+		t.Errorf("blocks script = %q, want it as given", blocks.Script)
+	}
+
+	if g := ps.For("gamepad"); !g.Skip || g.Reason != "needs a gamepad" {
+		t.Errorf("gamepad skip %v, reason %q", g.Skip, g.Reason)
+	}
+	if other := ps.For("unknown"); other.Format != "avi" || other.Skip {
+		t.Errorf("unknown game = %+v, want the default", other)
+	}
+
+	var none *Profiles
+	if p := none.For("flappy"); !reflect.DeepEqual(p, Profile{}) {
+		t.Errorf("nil Profiles For = %+v, want the zero profile", p)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name, data, want string
+	}{
+		{"skip without a reason", `{"games": {"x": {"skip": true}}}`, "skip needs a reason"},
+		{"unknown field", `{"default": {"durration": "1s"}}`, "unknown field"},
+		{"bad duration", `{"default": {"duration": "soon"}}`, "invalid duration"},
+		{"negative duration", `{"default": {"duration": "-1s"}}`, "negative duration"},
+		{"unknown format", `{"games": {"x": {"format": "mp4"}}}`, "unknown format"},
+		{"bad quality", `{"default": {"quality": 101}}`, "out of range"},
+		{"bad window", `{"games": {"x": {"window": "640"}}}`, "window: invalid size"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := load(t, tt.data)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+// Package project finds the ebiten-test checkout the tools run from,
+// whose recorder patched games import and under which recordings go
+package project
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// ErrNotFound is returned when no directory at or above the start is the
+// project
+var ErrNotFound = errors.New("project: not found: run from the ebiten-test checkout")
+
+// FindRoot returns the project providing the recorder: the nearest
+// directory at or above dir with a go.mod and pkg/recorder
+func FindRoot(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "pkg", "recorder")); err == nil {
+			if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+				return dir, nil
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ErrNotFound
+		}
+		dir = parent
+	}
+}
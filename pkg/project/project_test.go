@@ -0,0 +1,31 @@
+package project
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindRoot(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"pkg/recorder", "games/flappy", "other/pkg/recorder"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// other has a pkg/recorder but no go.mod, so it's passed over
+	for _, dir := range []string{".", "games/flappy", "other/pkg"} {
+		got, err := FindRoot(filepath.Join(root, dir))
+		if err != nil || got != root {
+			t.Errorf("FindRoot(%s) = %q, %v; want %q", dir, got, err, root)
+		}
+	}
+	if _, err := FindRoot(t.TempDir()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("outside the project: err = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,43 @@
+package recorder
+
+import (
+	"main/pkg/recorder/audio"
+	"path/filepath"
+	"strings"
+)
+
+// AudioMode selects where a recording's audio goes
+type AudioMode int
+
+const (
+	AudioMux AudioMode = iota // PCM track inside the AVI
+	AudioWAV                  // <output>.wav next to the video
+)
+
+// WithAudio records the sound played through tap along with the video
+// Create the game's audio players with the tap's NewPlayer methods
+// Only AVI recordings have audio; GIF and WebP ignore it
+func WithAudio(tap *audio.Tap, mode AudioMode) Option {
+	return func(w *GameWrapper) {
+		w.audioTap, w.audioMode = tap, mode
+	}
+}
+
+// setAudio hands the audio to the recorder
+// WrapGame calls it once every option is applied, so the warning goes
+// to the logger from WithLogger wherever it was given
+func (w *GameWrapper) setAudio() {
+	if w.audioTap == nil {
+		return
+	}
+	if r, ok := w.recorder.(*MJPEGRecorder); ok {
+		r.SetAudio(w.audioTap, w.audioMode)
+	} else {
+		w.logger.Warn("audio is only recorded to AVI", LogKeyPath, w.recorder.GetOutputPath())
+	}
+}
+
+// wavPath returns the WAV path for a video at outputPath
+func wavPath(outputPath string) string {
+	return strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".wav"
+}
@@ -0,0 +1,340 @@
+// Package audio captures the sound a game plays through ebiten's audio
+// package, so it can be written next to a recording or muxed into it
+//
+// Create players through a Tap instead of the audio.Context:
+//
+//	tap := audio.NewTap(audioContext)
+//	player, err := tap.NewPlayer(stream) // instead of audioContext.NewPlayer
+//
+// Each time the recorder captures a video frame it asks the tap for the
+// audio of that frame with Mix. The tap follows every player's Position,
+// so the mix is what the speakers played over the same span of time
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	ebitenaudio "github.com/hajimehoshi/ebiten/v2/audio"
+)
+
+// Output format: 16-bit stereo PCM at the context's sample rate
+const (
+	channels      = 2
+	bytesPerFrame = 4
+)
+
+const (
+	// maxDrift is how far a source may drift from its player's position
+	// before it's snapped back; smaller drift is left alone so the
+	// audio doesn't click
+	maxDrift = 100 * time.Millisecond
+
+	// keepHistory is how much already-mixed audio a source keeps, so a
+	// player that fell slightly behind can still be resynced
+	keepHistory = 200 * time.Millisecond
+
+	// maxBuffered caps the audio kept per source, e.g. while nothing
+	// is recording
+	maxBuffered = 2 * time.Second
+)
+
+// Tap creates ebiten audio players and records what they play
+type Tap struct {
+	ctx     *ebitenaudio.Context
+	rate    int
+	mu      sync.Mutex
+	sources []*source
+
+	// Mix's buffers; mixing guards them, not mu, which players take
+	// while Mix asks them for their state
+	mixing  sync.Mutex
+	mix     []float32
+	mixed   []*source
+	players []playerState
+	done    []*source
+}
+
+// NewTap creates a tap for players of ctx
+func NewTap(ctx *ebitenaudio.Context) *Tap {
+	return &Tap{ctx: ctx, rate: ctx.SampleRate()}
+}
+
+// SampleRate returns the sample rate of the mixed audio
+func (t *Tap) SampleRate() int {
+	return t.rate
+}
+
+// NewPlayer is audio.Context.NewPlayer for 16-bit stereo streams
+func (t *Tap) NewPlayer(src io.Reader) (*ebitenaudio.Player, error) {
+	return t.newPlayer(src, 2, t.ctx.NewPlayer)
+}
+
+// NewPlayerF32 is audio.Context.NewPlayerF32 for 32-bit float stereo streams
+func (t *Tap) NewPlayerF32(src io.Reader) (*ebitenaudio.Player, error) {
+	return t.newPlayer(src, 4, t.ctx.NewPlayerF32)
+}
+
+// NewPlayerFromBytes is audio.Context.NewPlayerFromBytes
+func (t *Tap) NewPlayerFromBytes(src []byte) *ebitenaudio.Player {
+	p, err := t.NewPlayer(bytes.NewReader(src))
+	if err != nil {
+		panic(err) // NewPlayerFromBytes panics too; bytes.Reader can't fail
+	}
+	return p
+}
+
+// NewPlayerF32FromBytes is audio.Context.NewPlayerF32FromBytes
+func (t *Tap) NewPlayerF32FromBytes(src []byte) *ebitenaudio.Player {
+	p, err := t.NewPlayerF32(bytes.NewReader(src))
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func (t *Tap) newPlayer(src io.Reader, sampleSize int, create func(io.Reader) (*ebitenaudio.Player, error)) (*ebitenaudio.Player, error) {
+	s := &source{
+		tap:            t,
+		sampleSize:     sampleSize,
+		frameSize:      sampleSize * channels,
+		bytesPerSecond: t.rate * sampleSize * channels,
+	}
+	var r io.Reader = &tapReader{src: src, s: s}
+	// The player is seekable only if the stream is
+	if _, ok := src.(io.Seeker); ok {
+		r = &tapReadSeeker{tapReader{src: src, s: s}}
+	}
+	p, err := create(r)
+	if err != nil {
+		return nil, err
+	}
+	s.player = p
+	t.track(s)
+	return p, nil
+}
+
+// track adds s to the mix if it isn't already part of it
+func (t *Tap) track(s *source) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !s.tracked {
+		s.tracked = true
+		t.sources = append(t.sources, s)
+	}
+}
+
+// Mix returns the next frames sample frames of all players mixed
+// together, as interleaved 16-bit little-endian stereo
+// Call it once per captured video frame with that frame's share of
+// samples; silence is returned while nothing plays
+func (t *Tap) Mix(frames int) []byte {
+	t.mixing.Lock()
+	defer t.mixing.Unlock()
+
+	if cap(t.mix) < frames*channels {
+		t.mix = make([]float32, frames*channels)
+	}
+	mix := t.mix[:frames*channels]
+	clear(mix)
+
+	t.mu.Lock()
+	t.mixed = append(t.mixed[:0], t.sources...)
+	t.mu.Unlock()
+
+	// A player holds its own lock while it reads or seeks the stream,
+	// which takes the source's and the tap's, so its state is read with
+	// neither held
+	t.players = t.players[:0]
+	for _, s := range t.mixed {
+		t.players = append(t.players, playerState{
+			playing:  s.player.IsPlaying(),
+			position: s.player.Position(),
+			volume:   float32(s.player.Volume()),
+		})
+	}
+
+	t.done = t.done[:0]
+	for i, s := range t.mixed {
+		if !s.mixInto(mix, t.players[i]) {
+			t.done = append(t.done, s)
+		}
+	}
+	if len(t.done) > 0 {
+		t.untrack(t.done)
+	}
+	clear(t.mixed)
+	clear(t.done)
+
+	out := make([]byte, 0, frames*bytesPerFrame)
+	for _, v := range mix {
+		v = max(-1, min(1, v))
+		out = binary.LittleEndian.AppendUint16(out, uint16(int16(v*math.MaxInt16)))
+	}
+	return out
+}
+
+// untrack drops the finished sources from the mix
+// A source rewound since it was mixed is kept
+func (t *Tap) untrack(finished []*source) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	kept := t.sources[:0]
+	for _, s := range t.sources {
+		if slices.Contains(finished, s) && s.exhausted() {
+			s.tracked = false
+		} else {
+			kept = append(kept, s)
+		}
+	}
+	clear(t.sources[len(kept):])
+	t.sources = kept
+}
+
+// playerState is what Mix needs to know of a player
+type playerState struct {
+	playing  bool
+	position time.Duration
+	volume   float32
+}
+
+// source is one player's stream as read by the player
+type source struct {
+	tap            *Tap
+	tracked        bool // guarded by tap.mu
+	player         *ebitenaudio.Player
+	sampleSize     int // 2 for int16, 4 for float32
+	frameSize      int
+	bytesPerSecond int
+
+	mu     sync.Mutex
+	buf    []byte
+	base   int64 // stream offset of buf[0]
+	cursor int64 // stream offset of the next byte to mix
+	synced bool
+	eof    bool
+}
+
+// read records data the player read from the stream
+func (s *source) read(data []byte, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buf = append(s.buf, data...)
+	if over := len(s.buf) - s.bytes(maxBuffered); over > 0 {
+		s.drop(over)
+	}
+	if err == io.EOF {
+		s.eof = true
+	}
+}
+
+// seek follows the stream to offset, dropping what was read before
+// Seeks to the current end, which players do to learn the position,
+// keep the buffer
+func (s *source) seek(offset int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if offset == s.base+int64(len(s.buf)) {
+		return
+	}
+	s.buf = s.buf[:0]
+	s.base = offset
+	s.cursor = offset
+	s.eof = false
+	s.synced = false
+}
+
+// mixInto adds the source's share of the next len(mix)/2 frames to mix
+// It returns false once the stream is exhausted and the player stopped;
+// rewinding the player tracks it again
+func (s *source) mixInto(mix []float32, player playerState) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	end := s.base + int64(len(s.buf))
+	if !player.playing {
+		s.synced = false
+		return !s.eof
+	}
+
+	// Follow the player; its position is what's coming out of the speakers
+	pos := int64(s.bytes(player.position))
+	if drift := pos - s.cursor; !s.synced || max(drift, -drift) > int64(s.bytes(maxDrift)) {
+		s.cursor = pos
+		s.synced = true
+	}
+	s.cursor = max(s.cursor, s.base)
+
+	n := min(int64(len(mix)/channels*s.frameSize), max(end-s.cursor, 0))
+	data := s.buf[s.cursor-s.base : s.cursor-s.base+n]
+	for i := range len(data) / s.sampleSize {
+		mix[i] += s.sample(data[i*s.sampleSize:]) * player.volume
+	}
+	s.cursor += n
+	s.drop(int(s.cursor-s.base) - s.bytes(keepHistory))
+	return true
+}
+
+// exhausted reports whether the stream has been read to its end
+func (s *source) exhausted() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.eof
+}
+
+// sample decodes one sample at the start of b
+func (s *source) sample(b []byte) float32 {
+	if s.sampleSize == 2 {
+		return float32(int16(binary.LittleEndian.Uint16(b))) / (math.MaxInt16 + 1)
+	}
+	return math.Float32frombits(binary.LittleEndian.Uint32(b))
+}
+
+// bytes converts d to a whole number of sample frames in bytes
+func (s *source) bytes(d time.Duration) int {
+	n := int(d.Seconds() * float64(s.bytesPerSecond))
+	return n - n%s.frameSize
+}
+
+// drop discards the oldest n bytes of the buffer
+func (s *source) drop(n int) {
+	n -= n % s.frameSize
+	n = min(n, len(s.buf))
+	if n <= 0 {
+		return
+	}
+	s.buf = s.buf[:copy(s.buf, s.buf[n:])]
+	s.base += int64(n)
+	s.cursor = max(s.cursor, s.base)
+}
+
+// tapReader copies everything the player reads into its source
+type tapReader struct {
+	src io.Reader
+	s   *source
+}
+
+func (r *tapReader) Read(p []byte) (int, error) {
+	n, err := r.src.Read(p)
+	r.s.read(p[:n], err)
+	return n, err
+}
+
+// tapReadSeeker is a tapReader for seekable streams
+type tapReadSeeker struct {
+	tapReader
+}
+
+func (r *tapReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	n, err := r.src.(io.Seeker).Seek(offset, whence)
+	if err == nil {
+		r.s.seek(n)
+		r.s.tap.track(r.s)
+	}
+	return n, err
+}
@@ -0,0 +1,76 @@
+package audio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"os"
+)
+
+// WAVWriter writes 16-bit stereo PCM to a WAV file
+type WAVWriter struct {
+	f     *os.File
+	w     *bufio.Writer
+	bytes int64
+	err   error
+}
+
+// NewWAVWriter creates a WAV file at path
+// The sizes in the header are filled in by Close
+func NewWAVWriter(path string, sampleRate int) (*WAVWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w := &WAVWriter{f: f, w: bufio.NewWriter(f)}
+
+	h := make([]byte, 0, 44)
+	h = append(h, "RIFF"...)
+	h = binary.LittleEndian.AppendUint32(h, 0)
+	h = append(h, "WAVEfmt "...)
+	h = binary.LittleEndian.AppendUint32(h, 16)
+	h = binary.LittleEndian.AppendUint16(h, 1) // PCM
+	h = binary.LittleEndian.AppendUint16(h, channels)
+	h = binary.LittleEndian.AppendUint32(h, uint32(sampleRate))
+	h = binary.LittleEndian.AppendUint32(h, uint32(sampleRate*bytesPerFrame))
+	h = binary.LittleEndian.AppendUint16(h, bytesPerFrame)
+	h = binary.LittleEndian.AppendUint16(h, 16)
+	h = append(h, "data"...)
+	h = binary.LittleEndian.AppendUint32(h, 0)
+	if _, err := w.w.Write(h); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write appends interleaved 16-bit little-endian stereo samples
+func (w *WAVWriter) Write(pcm []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n, err := w.w.Write(pcm)
+	w.bytes += int64(n)
+	w.err = err
+	return n, err
+}
+
+// Close fills in the header sizes and closes the file
+func (w *WAVWriter) Close() error {
+	defer w.f.Close()
+	if w.err != nil {
+		return w.err
+	}
+	if err := w.w.Flush(); err != nil {
+		return err
+	}
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], uint32(36+w.bytes))
+	if _, err := w.f.WriteAt(b[:], 4); err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint32(b[:], uint32(w.bytes))
+	if _, err := w.f.WriteAt(b[:], 40); err != nil {
+		return err
+	}
+	return w.f.Close()
+}
@@ -0,0 +1,238 @@
+package recorder
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// defaultCardDuration is how long a card is shown if Duration is 0
+const defaultCardDuration = 3 * time.Second
+
+// Card is a still frame shown at the start (intro) or end (outro) of a
+// recording, from text or an image
+type Card struct {
+	// Title is drawn large in the middle of the frame
+	Title string
+
+	// Lines are drawn smaller, below the title
+	Lines []string
+
+	// Image replaces the text when set, fitted into the frame
+	Image image.Image
+
+	// Duration is how long the card is shown (0 = 3s)
+	Duration time.Duration
+
+	// Background and Foreground default to black and white
+	Background color.RGBA
+	Foreground color.RGBA
+}
+
+// TitleCard returns a card with the game's name, the ebiten version it
+// was built with and today's date
+func TitleCard(game string) Card {
+	c := Card{Title: game}
+	if v := ebitenVersion(); v != "" {
+		c.Lines = append(c.Lines, "Ebitengine "+v)
+	}
+	c.Lines = append(c.Lines, time.Now().Format("2006-01-02"))
+	return c
+}
+
+// ImageCard returns a card showing the PNG at path
+func ImageCard(path string) (Card, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Card{}, err
+	}
+	defer f.Close()
+
+	img, err := png.Decode(f)
+	if err != nil {
+		return Card{}, fmt.Errorf("recorder: card %s: %w", path, err)
+	}
+	return Card{Image: img}, nil
+}
+
+// WithIntro opens the recording with c
+// Defaults to RECORD_INTRO; see parseCard
+func WithIntro(c Card) Option {
+	return func(w *GameWrapper) {
+		w.intro = &c
+	}
+}
+
+// WithOutro closes the recording with c
+// Defaults to RECORD_OUTRO; see parseCard
+func WithOutro(c Card) Option {
+	return func(w *GameWrapper) {
+		w.outro = &c
+	}
+}
+
+// parseCard reads a card from an environment value:
+// "title" for TitleCard(game), a path ending in .png for ImageCard, or
+// any other text for a card with that title
+func parseCard(s, game string) (Card, error) {
+	switch {
+	case strings.EqualFold(s, "title"):
+		return TitleCard(game), nil
+	case strings.EqualFold(filepath.Ext(s), ".png"):
+		return ImageCard(s)
+	}
+	return Card{Title: s}, nil
+}
+
+// defaultCards reads RECORD_INTRO, RECORD_OUTRO and RECORD_WATERMARK for
+// whatever the options didn't set
+func (w *GameWrapper) defaultCards() {
+	for _, d := range []struct {
+		env  string
+		card **Card
+	}{{"RECORD_INTRO", &w.intro}, {"RECORD_OUTRO", &w.outro}} {
+		s := os.Getenv(d.env)
+		if s == "" || *d.card != nil {
+			continue
+		}
+		c, err := parseCard(s, w.gameName)
+		if err != nil {
+			w.logger.Warn("ignoring "+d.env, "error", err)
+			continue
+		}
+		*d.card = &c
+	}
+
+	if s := os.Getenv("RECORD_WATERMARK"); s != "" && w.watermark == nil {
+		wm, err := parseWatermark(s)
+		if err != nil {
+			w.logger.Warn("ignoring RECORD_WATERMARK", "error", err)
+			return
+		}
+		w.watermark = &watermarkLayer{wm: wm}
+	}
+}
+
+func (c Card) duration() time.Duration {
+	if c.Duration <= 0 {
+		return defaultCardDuration
+	}
+	return c.Duration
+}
+
+// render draws the card at width x height
+func (c Card) render(width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	bg := c.Background
+	if bg == (color.RGBA{}) {
+		bg = color.RGBA{A: 255}
+	}
+	fg := c.Foreground
+	if fg == (color.RGBA{}) {
+		fg = color.RGBA{255, 255, 255, 255}
+	}
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+
+	if c.Image != nil {
+		_, content := Transform{Width: width, Height: height}.layout(c.Image.Bounds())
+		draw.CatmullRom.Scale(dst, content, c.Image, c.Image.Bounds(), draw.Over, nil)
+		return dst
+	}
+
+	// Sizes follow the frame height so cards look the same at any size
+	title := cardFace(height / 9)
+	small := cardFace(height / 20)
+	titleH := title.Metrics().Height.Ceil()
+	lineH := small.Metrics().Height.Ceil() * 3 / 2
+	top := (height - titleH - len(c.Lines)*lineH) / 2
+	drawCentered(dst, title, c.Title, width/2, top+title.Metrics().Ascent.Ceil(), fg)
+	for i, line := range c.Lines {
+		y := top + titleH + i*lineH + lineH/4 + small.Metrics().Ascent.Ceil()
+		drawCentered(dst, small, line, width/2, y, fg)
+	}
+	return dst
+}
+
+// captureCard records c for its duration at width x height
+// It returns the video time the card took
+func (w *GameWrapper) captureCard(c *Card, width, height int) (time.Duration, error) {
+	img := ebiten.NewImageFromImage(c.render(width, height))
+	defer img.Deallocate()
+	if w.watermark != nil {
+		w.watermark.draw(img)
+	}
+
+	frames := int(c.duration().Seconds()*float64(w.recorder.FPS()) + 0.5)
+	for range frames {
+		if err := w.recorder.CaptureFrame(img); err != nil {
+			return 0, err
+		}
+		if w.events.onFrame != nil {
+			w.events.onFrame(w.recorder.FrameCount())
+		}
+	}
+	return time.Duration(frames) * time.Second / time.Duration(w.recorder.FPS()), nil
+}
+
+// ebitenVersion returns the ebiten module version from the build info
+func ebitenVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == "github.com/hajimehoshi/ebiten/v2" {
+			if dep.Replace != nil {
+				dep = dep.Replace
+			}
+			return dep.Version
+		}
+	}
+	return ""
+}
+
+var (
+	cardFont  *opentype.Font
+	cardFaces = map[int]font.Face{}
+)
+
+// cardFace returns Go Regular at size pixels
+// Faces are cached; like ebiten drawing, they're used from the game loop only
+func cardFace(size int) font.Face {
+	size = max(size, 8)
+	if face, ok := cardFaces[size]; ok {
+		return face
+	}
+	var err error
+	if cardFont == nil {
+		if cardFont, err = opentype.Parse(goregular.TTF); err != nil {
+			panic(err) // goregular is embedded, so this can't fail
+		}
+	}
+	face, err := opentype.NewFace(cardFont, &opentype.FaceOptions{Size: float64(size), DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		panic(err)
+	}
+	cardFaces[size] = face
+	return face
+}
+
+// drawCentered draws s centred on x with its baseline at y
+func drawCentered(dst draw.Image, face font.Face, s string, x, y int, clr color.Color) {
+	d := font.Drawer{Dst: dst, Src: image.NewUniform(clr), Face: face}
+	d.Dot = fixed.Point26_6{X: fixed.I(x) - d.MeasureString(s)/2, Y: fixed.I(y)}
+	d.DrawString(s)
+}
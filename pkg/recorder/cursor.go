@@ -0,0 +1,102 @@
+package recorder
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// defaultCursor is a 12x19 arrow; '#' is the outline, '.' the fill
+var defaultCursor = []string{
+	"#",
+	"##",
+	"#.#",
+	"#..#",
+	"#...#",
+	"#....#",
+	"#.....#",
+	"#......#",
+	"#.......#",
+	"#........#",
+	"#.........#",
+	"#......#####",
+	"#...#..#",
+	"#..##..#",
+	"#.#  #..#",
+	"##   #..#",
+	"#     #..#",
+	"      #..#",
+	"       ##",
+}
+
+var cursorHighlight = color.NRGBA{255, 220, 64, 128}
+
+// cursorLayer draws the mouse cursor, which ebiten's screen never
+// contains, on recorded frames
+type cursorLayer struct {
+	sprite  *ebiten.Image
+	hotX    int
+	hotY    int
+	visible bool
+	x, y    int
+	pressed bool
+}
+
+// WithCursor composites a cursor sprite at ebiten.CursorPosition into the
+// recorded video, with a highlight while a mouse button is held
+// sprite: the cursor image, or nil for a default arrow
+// hotX, hotY: the point of the sprite that sits on the cursor position
+//
+// The cursor isn't drawn while the game hides or captures it, since
+// such games draw their own
+func WithCursor(sprite image.Image, hotX, hotY int) Option {
+	return func(w *GameWrapper) {
+		c := &cursorLayer{hotX: hotX, hotY: hotY}
+		if sprite != nil {
+			c.sprite = ebiten.NewImageFromImage(sprite)
+		} else {
+			c.sprite = ebiten.NewImageFromImage(arrowCursor())
+			c.hotX, c.hotY = 0, 0
+		}
+		w.layers = append(w.layers, c)
+	}
+}
+
+// arrowCursor renders defaultCursor
+func arrowCursor() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 12, len(defaultCursor)))
+	for y, row := range defaultCursor {
+		for x, c := range row {
+			switch c {
+			case '#':
+				img.Set(x, y, color.Black)
+			case '.':
+				img.Set(x, y, color.White)
+			}
+		}
+	}
+	return img
+}
+
+func (c *cursorLayer) update() {
+	c.x, c.y = ebiten.CursorPosition()
+	c.visible = ebiten.CursorMode() == ebiten.CursorModeVisible
+	c.pressed = ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) ||
+		ebiten.IsMouseButtonPressed(ebiten.MouseButtonRight) ||
+		ebiten.IsMouseButtonPressed(ebiten.MouseButtonMiddle)
+}
+
+func (c *cursorLayer) draw(dst *ebiten.Image) {
+	// The position is kept when the cursor leaves the window
+	if !c.visible || !image.Pt(c.x, c.y).In(dst.Bounds()) {
+		return
+	}
+	if c.pressed {
+		vector.FillCircle(dst, float32(c.x), float32(c.y), 12, cursorHighlight, true)
+	}
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(float64(c.x-c.hotX), float64(c.y-c.hotY))
+	dst.DrawImage(c.sprite, op)
+}
@@ -0,0 +1,319 @@
+package encode
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrAVITooLarge is returned when a chunk would push the AVI past the
+// 32-bit offsets of the AVI 1.0 format
+var ErrAVITooLarge = errors.New("encode: AVI file too large")
+
+// AVI chunk IDs and flags
+const (
+	aviChunkVideo  = "00dc"
+	aviChunkAudio  = "01wb"
+	aviIfKeyframe  = 0x10
+	avifHasIndex   = 0x10
+	avifInterleave = 0x100
+	aviMaxSize     = 4200000000
+)
+
+// AVIWriter writes an MJPEG AVI with an optional 16-bit stereo PCM
+// audio track
+// It implements mjpeg.AviWriter, so it can stand in for icza/mjpeg
+type AVIWriter struct {
+	f        *os.File
+	w        *bufio.Writer
+	pos      int64
+	moviPos  int64
+	fpsRate  int // the frame rate is fpsRate/fpsScale
+	fpsScale int
+	rate     int
+	frames   int
+	samples  int64
+	maxChunk int
+	index    []aviIndexEntry
+	patches  aviPatches
+	err      error
+}
+
+type aviIndexEntry struct {
+	id     string
+	offset uint32
+	size   uint32
+}
+
+// aviPatches are the header fields only known once writing is done
+type aviPatches struct {
+	riffSize    int64
+	totalFrames int64
+	bufferSize  int64
+	videoLength int64
+	videoBuffer int64
+	audioLength int64
+	audioBuffer int64
+	moviSize    int64
+}
+
+// NewAVIWriter creates an AVI file at path
+// width, height: frame size in pixels
+// fps: video frame rate
+// audioRate: sample rate of the PCM track (0 = no audio track)
+func NewAVIWriter(path string, width, height, fps, audioRate int) (*AVIWriter, error) {
+	return NewAVIWriterRate(path, width, height, fps, 1, audioRate)
+}
+
+// NewAVIWriterRate is NewAVIWriter with the frame rate given the way AVI
+// stores it, as the fraction rate/scale, e.g. 30000/1001 for 29.97 fps
+func NewAVIWriterRate(path string, width, height, rate, scale, audioRate int) (*AVIWriter, error) {
+	if rate <= 0 || scale <= 0 {
+		return nil, fmt.Errorf("encode: invalid AVI frame rate %d/%d", rate, scale)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	a := &AVIWriter{f: f, w: bufio.NewWriter(f), fpsRate: rate, fpsScale: scale, rate: audioRate}
+	a.writeHeader(width, height)
+	if a.err != nil {
+		f.Close()
+		return nil, a.err
+	}
+	return a, nil
+}
+
+func (a *AVIWriter) writeHeader(width, height int) {
+	streams := 1
+	flags := avifHasIndex
+	if a.rate > 0 {
+		streams = 2
+		flags |= avifInterleave
+	}
+
+	a.str("RIFF")
+	a.patches.riffSize = a.placeholder()
+	a.str("AVI ")
+
+	a.str("LIST")
+	hdrl := a.placeholder()
+	a.str("hdrl")
+	a.str("avih")
+	a.u32(56)
+	a.u32(uint32(1000000 * int64(a.fpsScale) / int64(a.fpsRate))) // dwMicroSecPerFrame
+	a.u32(0)                                                      // dwMaxBytesPerSec
+	a.u32(0)                                                      // dwPaddingGranularity
+	a.u32(uint32(flags))
+	a.patches.totalFrames = a.placeholder()
+	a.u32(0) // dwInitialFrames
+	a.u32(uint32(streams))
+	a.patches.bufferSize = a.placeholder()
+	a.u32(uint32(width))
+	a.u32(uint32(height))
+	a.zeros(16) // dwReserved
+
+	// Video stream
+	a.str("LIST")
+	strl := a.placeholder()
+	a.str("strl")
+	a.str("strh")
+	a.u32(56)
+	a.str("vids")
+	a.str("MJPG")
+	a.u32(0) // dwFlags
+	a.u32(0) // wPriority, wLanguage
+	a.u32(0) // dwInitialFrames
+	a.u32(uint32(a.fpsScale))
+	a.u32(uint32(a.fpsRate))
+	a.u32(0) // dwStart
+	a.patches.videoLength = a.placeholder()
+	a.patches.videoBuffer = a.placeholder()
+	a.u32(0xffffffff) // dwQuality, driver default
+	a.u32(0)          // dwSampleSize, one frame per chunk
+	a.zeros(8)        // rcFrame
+	a.str("strf")
+	a.u32(40)
+	a.u32(40) // biSize
+	a.u32(uint32(width))
+	a.u32(uint32(height))
+	a.u16(1)  // biPlanes
+	a.u16(24) // biBitCount
+	a.str("MJPG")
+	a.u32(uint32(width * height * 3))
+	a.zeros(16) // resolution and palette
+	a.finish(strl)
+
+	// Audio stream: 16-bit stereo PCM, one sample frame per block
+	if a.rate > 0 {
+		a.str("LIST")
+		strl := a.placeholder()
+		a.str("strl")
+		a.str("strh")
+		a.u32(56)
+		a.str("auds")
+		a.u32(0) // fccHandler
+		a.u32(0) // dwFlags
+		a.u32(0) // wPriority, wLanguage
+		a.u32(0) // dwInitialFrames
+		a.u32(4) // dwScale, block align
+		a.u32(uint32(a.rate * 4))
+		a.u32(0) // dwStart
+		a.patches.audioLength = a.placeholder()
+		a.patches.audioBuffer = a.placeholder()
+		a.u32(0xffffffff)
+		a.u32(4) // dwSampleSize
+		a.zeros(8)
+		a.str("strf")
+		a.u32(18)
+		a.u16(1) // WAVE_FORMAT_PCM
+		a.u16(2)
+		a.u32(uint32(a.rate))
+		a.u32(uint32(a.rate * 4))
+		a.u16(4)
+		a.u16(16)
+		a.u16(0) // cbSize
+		a.finish(strl)
+	}
+	a.finish(hdrl)
+
+	a.str("LIST")
+	a.patches.moviSize = a.placeholder()
+	a.moviPos = a.pos
+	a.str("movi")
+}
+
+// AddFrame appends a JPEG-encoded video frame
+func (a *AVIWriter) AddFrame(jpegData []byte) error {
+	if err := a.chunk(aviChunkVideo, jpegData); err != nil {
+		return err
+	}
+	a.frames++
+	return nil
+}
+
+// AddAudio appends interleaved 16-bit little-endian stereo samples
+// Write audio after the frame it accompanies, so players stay in sync
+func (a *AVIWriter) AddAudio(pcm []byte) error {
+	if a.rate == 0 {
+		return errors.New("encode: AVI has no audio track")
+	}
+	if len(pcm) == 0 {
+		return nil
+	}
+	if err := a.chunk(aviChunkAudio, pcm); err != nil {
+		return err
+	}
+	a.samples += int64(len(pcm) / 4)
+	return nil
+}
+
+func (a *AVIWriter) chunk(id string, data []byte) error {
+	if a.err != nil {
+		return a.err
+	}
+	if a.pos+int64(len(data))+int64(len(a.index)+1)*16 > aviMaxSize {
+		return ErrAVITooLarge
+	}
+	a.index = append(a.index, aviIndexEntry{id: id, offset: uint32(a.pos - a.moviPos), size: uint32(len(data))})
+	a.maxChunk = max(a.maxChunk, len(data))
+	a.str(id)
+	a.u32(uint32(len(data)))
+	a.bytes(data)
+	if len(data)%2 == 1 {
+		a.zeros(1)
+	}
+	return a.err
+}
+
+// Close writes the index, fills in the header and closes the file
+func (a *AVIWriter) Close() error {
+	defer a.f.Close()
+
+	a.finishAt(a.patches.moviSize, a.moviPos)
+	a.str("idx1")
+	a.u32(uint32(len(a.index) * 16))
+	for _, e := range a.index {
+		a.str(e.id)
+		a.u32(aviIfKeyframe)
+		a.u32(e.offset)
+		a.u32(e.size)
+	}
+	end := a.pos
+	if a.err == nil {
+		a.err = a.w.Flush()
+	}
+
+	a.patch(a.patches.riffSize, uint32(end-8))
+	a.patch(a.patches.totalFrames, uint32(a.frames))
+	a.patch(a.patches.bufferSize, uint32(a.maxChunk))
+	a.patch(a.patches.videoLength, uint32(a.frames))
+	a.patch(a.patches.videoBuffer, uint32(a.maxChunk))
+	if a.rate > 0 {
+		a.patch(a.patches.audioLength, uint32(a.samples))
+		a.patch(a.patches.audioBuffer, uint32(a.maxChunk))
+	}
+	return a.err
+}
+
+// finish patches the size of the chunk whose size field is at sizePos
+func (a *AVIWriter) finish(sizePos int64) {
+	a.finishAt(sizePos, sizePos+4)
+}
+
+// finishAt patches the size field at sizePos with the bytes written
+// since start
+func (a *AVIWriter) finishAt(sizePos, start int64) {
+	size := a.pos - start
+	if a.err != nil {
+		return
+	}
+	// Flush first so the field being patched is already in the file
+	if a.err = a.w.Flush(); a.err != nil {
+		return
+	}
+	a.patch(sizePos, uint32(size))
+}
+
+func (a *AVIWriter) patch(at int64, v uint32) {
+	if a.err != nil {
+		return
+	}
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	_, a.err = a.f.WriteAt(b[:], at)
+}
+
+// placeholder writes a zero uint32 and returns its offset
+func (a *AVIWriter) placeholder() int64 {
+	at := a.pos
+	a.u32(0)
+	return at
+}
+
+func (a *AVIWriter) bytes(b []byte) {
+	if a.err != nil {
+		return
+	}
+	var n int
+	n, a.err = a.w.Write(b)
+	a.pos += int64(n)
+}
+
+func (a *AVIWriter) str(s string) {
+	a.bytes([]byte(s))
+}
+
+func (a *AVIWriter) u32(v uint32) {
+	a.bytes(binary.LittleEndian.AppendUint32(nil, v))
+}
+
+func (a *AVIWriter) u16(v uint16) {
+	a.bytes(binary.LittleEndian.AppendUint16(nil, v))
+}
+
+func (a *AVIWriter) zeros(n int) {
+	a.bytes(make([]byte, n))
+}
@@ -0,0 +1,150 @@
+// Package encode holds the recorders' encoders, which need no ebiten:
+// the MJPEG AVI writer, JPEG frames, palette conversion and the animated
+// GIF and WebP writers. Tools that convert or cut recordings use it to
+// write exactly what the recorders write
+package encode
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"io"
+	"sort"
+
+	"github.com/HugoSmits86/nativewebp"
+)
+
+// JPEG encodes a frame for an MJPEG AVI
+// quality: JPEG quality (1-100)
+func JPEG(img image.Image, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Paletted converts img to pal for GIF and WebP frames
+// The recorders use palette.Plan9 without dithering, which is fast
+// enough to run every frame
+func Paletted(img image.Image, pal color.Palette, dither bool) *image.Paletted {
+	b := img.Bounds()
+	p := image.NewPaletted(b, pal)
+	if dither {
+		draw.FloydSteinberg.Draw(p, b, img, b.Min)
+	} else {
+		draw.Draw(p, b, img, b.Min, draw.Src)
+	}
+	return p
+}
+
+// WriteGIF writes an animated GIF that loops forever
+// delays: each frame's delay in 100ths of a second
+func WriteGIF(w io.Writer, frames []*image.Paletted, delays []int) error {
+	return gif.EncodeAll(w, &gif.GIF{
+		Image: frames,
+		Delay: delays,
+	})
+}
+
+// WriteWebP writes a lossless animated WebP that loops forever
+// durations: each frame's duration in milliseconds
+func WriteWebP(w io.Writer, frames []image.Image, durations []uint) error {
+	// Disposal 0 keeps each frame; they're all full frames anyway
+	animation := &nativewebp.Animation{
+		Images:          frames,
+		Durations:       durations,
+		Disposals:       make([]uint, len(frames)),
+		LoopCount:       0,          // 0 = infinite loop
+		BackgroundColor: 0x00000000, // transparent black
+	}
+	return nativewebp.EncodeAll(w, animation, nil)
+}
+
+// AdaptivePalette picks up to n colors for imgs by median cut, which
+// suits a recording's colors far better than Plan9
+// Pixels are sampled, so a long recording doesn't cost much more than a
+// short one
+func AdaptivePalette(imgs []image.Image, n int) color.Palette {
+	n = min(max(n, 2), 256)
+	const maxSamples = 1 << 18
+	total := 0
+	for _, img := range imgs {
+		total += img.Bounds().Dx() * img.Bounds().Dy()
+	}
+	step := max(total/maxSamples, 1)
+
+	var pixels [][3]uint8
+	i := 0
+	for _, img := range imgs {
+		b := img.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if i++; i%step != 0 {
+					continue
+				}
+				c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+				pixels = append(pixels, [3]uint8{c.R, c.G, c.B})
+			}
+		}
+	}
+	if len(pixels) == 0 {
+		return color.Palette{color.Black, color.White}
+	}
+
+	// Split the box with the widest channel range at its median until
+	// there are n boxes, then average each
+	boxes := []colorBox{newColorBox(pixels)}
+	for len(boxes) < n {
+		best := 0
+		for i, box := range boxes {
+			if box.spread > boxes[best].spread {
+				best = i
+			}
+		}
+		box := boxes[best]
+		if box.spread == 0 {
+			break // every box is a single color
+		}
+		ch := box.channel
+		sort.Slice(box.pixels, func(i, j int) bool { return box.pixels[i][ch] < box.pixels[j][ch] })
+		mid := len(box.pixels) / 2
+		boxes[best] = newColorBox(box.pixels[:mid])
+		boxes = append(boxes, newColorBox(box.pixels[mid:]))
+	}
+
+	pal := make(color.Palette, 0, len(boxes))
+	for _, box := range boxes {
+		var r, g, b int
+		for _, p := range box.pixels {
+			r, g, b = r+int(p[0]), g+int(p[1]), b+int(p[2])
+		}
+		n := len(box.pixels)
+		pal = append(pal, color.RGBA{uint8(r / n), uint8(g / n), uint8(b / n), 255})
+	}
+	return pal
+}
+
+// colorBox is a median cut box and its widest channel
+type colorBox struct {
+	pixels  [][3]uint8
+	channel int
+	spread  int
+}
+
+func newColorBox(pixels [][3]uint8) colorBox {
+	box := colorBox{pixels: pixels}
+	for ch := 0; ch < 3; ch++ {
+		lo, hi := uint8(255), uint8(0)
+		for _, p := range pixels {
+			lo, hi = min(lo, p[ch]), max(hi, p[ch])
+		}
+		if r := int(hi) - int(lo); r > box.spread {
+			box.channel, box.spread = ch, r
+		}
+	}
+	return box
+}
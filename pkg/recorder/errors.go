@@ -0,0 +1,20 @@
+package recorder
+
+import "fmt"
+
+// RecordingError is returned from GameWrapper.Update when a recording
+// can't be started or saved, and passed to OnError for every failure
+// including dropped frames
+type RecordingError struct {
+	Op   string // "start", "capture" or "save"
+	Path string // output path of the recording
+	Err  error
+}
+
+func (e *RecordingError) Error() string {
+	return fmt.Sprintf("recorder: %s %s: %v", e.Op, e.Path, e.Err)
+}
+
+func (e *RecordingError) Unwrap() error {
+	return e.Err
+}
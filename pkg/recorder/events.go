@@ -0,0 +1,51 @@
+package recorder
+
+import "time"
+
+// eventHooks holds the lifecycle callbacks registered on a GameWrapper
+// All callbacks run synchronously on the game goroutine, so they must
+// not block; hand work off to another goroutine if needed
+type eventHooks struct {
+	onStart func(path string)
+	onFrame func(frame int)
+	onStop  func(frames int, elapsed time.Duration)
+	onSaved func(path string, frames int, duration time.Duration)
+	onError func(err *RecordingError)
+}
+
+// OnStart registers a callback run when a recording starts
+func OnStart(fn func(path string)) Option {
+	return func(w *GameWrapper) {
+		w.events.onStart = fn
+	}
+}
+
+// OnFrame registers a callback run after each captured frame
+// frame is the number of frames captured so far
+func OnFrame(fn func(frame int)) Option {
+	return func(w *GameWrapper) {
+		w.events.onFrame = fn
+	}
+}
+
+// OnStop registers a callback run when a recording stops, before the
+// output file is finalized
+func OnStop(fn func(frames int, elapsed time.Duration)) Option {
+	return func(w *GameWrapper) {
+		w.events.onStop = fn
+	}
+}
+
+// OnSaved registers a callback run once the output file has been written
+func OnSaved(fn func(path string, frames int, duration time.Duration)) Option {
+	return func(w *GameWrapper) {
+		w.events.onSaved = fn
+	}
+}
+
+// OnError registers a callback run when starting, capturing or saving fails
+func OnError(fn func(err *RecordingError)) Option {
+	return func(w *GameWrapper) {
+		w.events.onError = fn
+	}
+}
@@ -0,0 +1,133 @@
+package recorder
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Format is a recording's file format
+type Format int
+
+const (
+	FormatAVI  Format = iota // MJPEG AVI
+	FormatGIF                // animated GIF
+	FormatWebP               // animated WebP
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatAVI:
+		return "avi"
+	case FormatGIF:
+		return "gif"
+	case FormatWebP:
+		return "webp"
+	}
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
+// Ext returns the file extension for f, with the dot
+func (f Format) Ext() string {
+	return "." + f.String()
+}
+
+// ParseFormat parses "avi", "gif" or "webp"
+func ParseFormat(s string) (Format, error) {
+	for _, f := range []Format{FormatAVI, FormatGIF, FormatWebP} {
+		if strings.EqualFold(strings.TrimPrefix(s, "."), f.String()) {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("recorder: unknown format %q", s)
+}
+
+// FormatOf returns the format for a recording at path by its extension
+// Anything other than .gif and .webp is an AVI
+func FormatOf(path string) Format {
+	f, err := ParseFormat(filepath.Ext(path))
+	if err != nil {
+		return FormatAVI
+	}
+	return f
+}
+
+// frameRecorder is what GameWrapper needs from a recorder
+type frameRecorder interface {
+	SetLogger(logger *slog.Logger)
+	SetTransform(t Transform)
+	SetResizePolicy(p ResizePolicy)
+	Start(width, height int) error
+	Stop() error
+	CaptureFrame(screen *ebiten.Image) error
+	IsRecording() bool
+	FrameCount() int
+	FPS() int
+	repeatNext()
+	GetOutputPath() string
+}
+
+// newFrameRecorder creates the recorder for outputPath's format
+// maxFrames: frame limit (0 = the recorder's default)
+// quality only applies to AVI
+func newFrameRecorder(outputPath string, maxFrames, quality, fps int) frameRecorder {
+	switch FormatOf(outputPath) {
+	case FormatGIF:
+		return gifFrameRecorder{NewGIFRecorder(maxFrames, fps, outputPath)}
+	case FormatWebP:
+		return webpFrameRecorder{NewWebPRecorder(maxFrames, fps, outputPath)}
+	}
+	return NewMJPEGRecorder(maxFrames, fps, outputPath, quality)
+}
+
+// gifFrameRecorder adapts GIFRecorder, which keeps frames in memory and
+// saves them once stopped
+type gifFrameRecorder struct {
+	*GIFRecorder
+}
+
+func (r gifFrameRecorder) Start(width, height int) error {
+	r.GIFRecorder.Start()
+	return nil
+}
+
+func (r gifFrameRecorder) Stop() error {
+	r.GIFRecorder.Stop()
+	return r.SaveGIF()
+}
+
+func (r gifFrameRecorder) CaptureFrame(screen *ebiten.Image) error {
+	r.GIFRecorder.CaptureFrame(screen)
+	return nil
+}
+
+func (r gifFrameRecorder) FPS() int {
+	return r.fps
+}
+
+// webpFrameRecorder adapts WebPRecorder like gifFrameRecorder
+type webpFrameRecorder struct {
+	*WebPRecorder
+}
+
+func (r webpFrameRecorder) Start(width, height int) error {
+	r.WebPRecorder.Start()
+	return nil
+}
+
+func (r webpFrameRecorder) Stop() error {
+	r.WebPRecorder.Stop()
+	return r.SaveWebP()
+}
+
+func (r webpFrameRecorder) CaptureFrame(screen *ebiten.Image) error {
+	r.WebPRecorder.CaptureFrame(screen)
+	return nil
+}
+
+func (r webpFrameRecorder) FPS() int {
+	return r.fps
+}
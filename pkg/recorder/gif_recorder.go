@@ -3,9 +3,10 @@ package recorder
 import (
 	"image"
 	"image/color/palette"
-	"image/draw"
-	"image/gif"
+	"log/slog"
+	"main/pkg/recorder/encode"
 	"os"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 )
@@ -19,6 +20,11 @@ type GIFRecorder struct {
 	fps         int
 	frameCount  int
 	outputPath  string
+	stats       statsCollector
+	transform   frameTransform
+	size        sizeGuard
+	segment     int
+	logger      *slog.Logger
 }
 
 // NewGIFRecorder creates a new GIF recorder
@@ -39,22 +45,54 @@ func NewGIFRecorder(maxFrames, fps int, outputPath string) *GIFRecorder {
 		maxFrames:  maxFrames,
 		fps:        fps,
 		outputPath: outputPath,
+		logger:     defaultLogger(),
 	}
 }
 
+// SetLogger sets the logger used for start, save and dropped-frame records
+func (r *GIFRecorder) SetLogger(logger *slog.Logger) {
+	r.logger = logger
+}
+
+// SetTransform crops, scales and letterboxes frames before encoding
+// Call it before Start
+func (r *GIFRecorder) SetTransform(t Transform) {
+	r.transform = frameTransform{t: t}
+}
+
+// SetResizePolicy sets what happens when the frame size changes
+// mid-recording; the default is ResizeScale
+func (r *GIFRecorder) SetResizePolicy(p ResizePolicy) {
+	r.size.policy = p
+}
+
 // Start begins recording frames
 func (r *GIFRecorder) Start() {
 	r.recording = true
 	r.frames = r.frames[:0]
 	r.delays = r.delays[:0]
 	r.frameCount = 0
+	r.segment = 0
+	r.size.start(0, 0)
+	r.stats.reset()
+
+	r.logger.Info("recording started", LogKeyPath, r.outputPath, "fps", r.fps)
 }
 
 // Stop stops recording frames
 func (r *GIFRecorder) Stop() {
+	if r.recording {
+		r.stats.finish()
+	}
 	r.recording = false
 }
 
+// Stats returns a snapshot of the current or last recording's health
+// BytesWritten is filled in by SaveGIF
+func (r *GIFRecorder) Stats() Stats {
+	return r.stats.snapshot(r.fps)
+}
+
 // IsRecording returns true if currently recording
 func (r *GIFRecorder) IsRecording() bool {
 	return r.recording
@@ -65,6 +103,12 @@ func (r *GIFRecorder) FrameCount() int {
 	return r.frameCount
 }
 
+// repeatNext marks the next captured frame as a repeat of the last one,
+// made to keep pace with the wall clock, so it isn't counted as unchanged
+func (r *GIFRecorder) repeatNext() {
+	r.stats.repeatNext = true
+}
+
 // CaptureFrame captures the current screen frame
 // Call this from your game's Draw method
 func (r *GIFRecorder) CaptureFrame(screen *ebiten.Image) {
@@ -74,6 +118,7 @@ func (r *GIFRecorder) CaptureFrame(screen *ebiten.Image) {
 
 	// Check if we've hit the max frame limit
 	if r.maxFrames > 0 && r.frameCount >= r.maxFrames {
+		r.logger.Warn("frame limit reached, stopping", LogKeyPath, r.outputPath, LogKeyFrames, r.frameCount)
 		r.Stop()
 		return
 	}
@@ -82,8 +127,10 @@ func (r *GIFRecorder) CaptureFrame(screen *ebiten.Image) {
 	w, h := bounds.Dx(), bounds.Dy()
 
 	// Read pixels from the screen
+	captureStart := time.Now()
 	pixels := make([]byte, 4*w*h)
 	screen.ReadPixels(pixels)
+	captureTime := time.Since(captureStart)
 
 	// Convert to RGBA image
 	rgba := &image.RGBA{
@@ -91,33 +138,74 @@ func (r *GIFRecorder) CaptureFrame(screen *ebiten.Image) {
 		Stride: 4 * w,
 		Rect:   bounds,
 	}
+	rgba, newSegment := r.size.fit(rgba, r.logger, r.outputPath)
+	if newSegment {
+		r.nextSegment()
+	}
 
 	// Convert to paletted image for GIF
-	paletted := image.NewPaletted(bounds, palette.Plan9)
-	draw.Draw(paletted, bounds, rgba, bounds.Min, draw.Src)
+	encodeStart := time.Now()
+	rgba = r.transform.apply(rgba)
+	paletted := encode.Paletted(rgba, palette.Plan9, false)
+	r.stats.encodeTime += time.Since(encodeStart)
 
 	r.frames = append(r.frames, paletted)
 	// GIF delay is in 100ths of a second
 	r.delays = append(r.delays, 100/r.fps)
+	r.stats.frame(pixels, captureTime)
 	r.frameCount++
 }
 
 // SaveGIF saves the recorded frames as an animated GIF
 func (r *GIFRecorder) SaveGIF() error {
+	return r.saveTo(segmentPath(r.outputPath, r.segment))
+}
+
+// nextSegment saves the frames so far and continues in a new segment
+// at the size of the next frame
+func (r *GIFRecorder) nextSegment() {
+	path := segmentPath(r.outputPath, r.segment)
+	if err := r.saveTo(path); err != nil {
+		r.logger.Warn("failed to save segment", LogKeyPath, path, "error", err)
+	}
+	r.frames = r.frames[:0]
+	r.delays = r.delays[:0]
+	r.segment++
+	r.size.start(0, 0)
+}
+
+// saveTo writes the frames of the current segment to path
+func (r *GIFRecorder) saveTo(path string) error {
 	if len(r.frames) == 0 {
 		return nil // Nothing to save
 	}
 
-	f, err := os.Create(r.outputPath)
+	encodeStart := time.Now()
+	f, err := os.Create(path)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
-	return gif.EncodeAll(f, &gif.GIF{
-		Image: r.frames,
-		Delay: r.delays,
-	})
+	if err := encode.WriteGIF(f, r.frames, r.delays); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	r.stats.encodeTime += time.Since(encodeStart)
+
+	r.stats.bytes = fileSize(path)
+	stats := r.Stats()
+	r.logger.Info("recording saved",
+		LogKeyPath, path,
+		LogKeyFrames, r.frameCount,
+		LogKeyBytes, stats.BytesWritten,
+		LogKeyElapsed, stats.WallDuration)
+	if err := writeStatsSidecar(path, stats); err != nil {
+		r.logger.Warn("failed to write stats", LogKeyPath, path, "error", err)
+	}
+	return nil
 }
 
 // GetOutputPath returns the configured output path
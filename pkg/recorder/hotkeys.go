@@ -0,0 +1,51 @@
+package recorder
+
+import (
+	input "github.com/quasilyte/ebitengine-input"
+)
+
+// Wrapper actions
+// These live in the GameWrapper's own input.System, so they never
+// collide with the action IDs of the wrapped game
+const (
+	ActionUnknown input.Action = iota
+	ActionRecordToggle
+	ActionPause
+	ActionScreenshot
+	ActionSaveAndQuit
+)
+
+// DefaultKeymap returns the hotkeys GameWrapper uses unless overridden
+// Function keys are used so the defaults don't clash with common game
+// bindings such as R (reload) or Escape (pause menu)
+// Games bind most gamepad buttons, so only pause, which loses nothing,
+// is on the gamepad, on the rarely used Home button; stopping a
+// recording or quitting from a gamepad has to be bound with WithKeymap
+func DefaultKeymap() input.Keymap {
+	return input.Keymap{
+		ActionRecordToggle: {input.KeyF9},
+		ActionPause:        {input.KeyF10, input.KeyGamepadHome},
+		ActionScreenshot:   {input.KeyF8},
+		ActionSaveAndQuit:  {input.KeyF12},
+	}
+}
+
+// WithKeymap overrides the bindings of the actions present in keymap
+// Actions not listed keep their default bindings
+// An action mapped to an empty key list is disabled
+func WithKeymap(keymap input.Keymap) Option {
+	return func(w *GameWrapper) {
+		for action, keys := range keymap {
+			w.keymap[action] = append([]input.Key(nil), keys...)
+		}
+	}
+}
+
+// WithoutHotkeys disables the given wrapper actions
+func WithoutHotkeys(actions ...input.Action) Option {
+	return func(w *GameWrapper) {
+		for _, action := range actions {
+			delete(w.keymap, action)
+		}
+	}
+}
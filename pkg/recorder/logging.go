@@ -0,0 +1,53 @@
+package recorder
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Attribute keys shared by every log record in this package
+// Batch tooling can rely on these when parsing JSON logs
+const (
+	LogKeyGame    = "game"
+	LogKeyPath    = "path"
+	LogKeyFrames  = "frames"
+	LogKeyBytes   = "bytes"
+	LogKeyElapsed = "elapsed"
+)
+
+// NewJSONLogger returns a logger that writes one JSON object per record
+// Useful for batch runs whose output is parsed by other tools
+func NewJSONLogger(w io.Writer) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, nil))
+}
+
+// defaultLogger returns the logger used when none is configured
+// Setting RECORD_LOG_FORMAT=json switches to JSON records on stderr
+func defaultLogger() *slog.Logger {
+	if strings.EqualFold(os.Getenv("RECORD_LOG_FORMAT"), "json") {
+		return NewJSONLogger(os.Stderr)
+	}
+	return slog.Default()
+}
+
+// defaultGameName names the game in log records when WithGameName isn't used
+// RECORD_GAME wins, otherwise the executable name is used, which for
+// `go run .` is the example's directory name
+func defaultGameName() string {
+	if name := os.Getenv("RECORD_GAME"); name != "" {
+		return name
+	}
+	return strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")
+}
+
+// fileSize returns the size of path in bytes, or 0 if it can't be read
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
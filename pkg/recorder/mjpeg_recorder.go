@@ -1,9 +1,12 @@
 package recorder
 
 import (
-	"bytes"
+	"errors"
 	"image"
-	"image/jpeg"
+	"log/slog"
+	"main/pkg/recorder/audio"
+	"main/pkg/recorder/encode"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/icza/mjpeg"
@@ -22,6 +25,20 @@ type MJPEGRecorder struct {
 	width       int32
 	height      int32
 	jpegQuality int
+	stats       statsCollector
+	transform   frameTransform
+	logger      *slog.Logger
+
+	// Size changes
+	size    sizeGuard
+	segment int
+
+	// Audio
+	audio       *audio.Tap
+	audioMode   AudioMode
+	aviAudio    *encode.AVIWriter
+	wav         *audio.WAVWriter
+	audioFrames int64
 }
 
 // NewMJPEGRecorder creates a new MJPEG/AVI recorder (pure Go, no CGO/ffmpeg)
@@ -45,30 +62,110 @@ func NewMJPEGRecorder(maxFrames int, fps int, outputPath string, jpegQuality int
 		fps:         int32(fps),
 		outputPath:  outputPath,
 		jpegQuality: jpegQuality,
+		logger:      defaultLogger(),
 	}
 }
 
+// SetLogger sets the logger used for start, save and dropped-frame records
+func (r *MJPEGRecorder) SetLogger(logger *slog.Logger) {
+	r.logger = logger
+}
+
+// SetTransform crops, scales and letterboxes frames before encoding
+// Call it before Start
+func (r *MJPEGRecorder) SetTransform(t Transform) {
+	r.transform = frameTransform{t: t}
+}
+
+// SetAudio records the sound played through tap with the video
+// mode: AudioMux for a PCM track in the AVI, AudioWAV for a WAV file
+// next to it
+// Call it before Start
+func (r *MJPEGRecorder) SetAudio(tap *audio.Tap, mode AudioMode) {
+	r.audio = tap
+	r.audioMode = mode
+}
+
+// FPS returns the frame rate of the output AVI
+func (r *MJPEGRecorder) FPS() int {
+	return int(r.fps)
+}
+
+// SetResizePolicy sets what happens when the frame size changes
+// mid-recording; the default is ResizeScale
+func (r *MJPEGRecorder) SetResizePolicy(p ResizePolicy) {
+	r.size.policy = p
+}
+
 // Start begins recording frames
+// width, height: the game's frame size; frames of another size are
+// handled by the resize policy
 func (r *MJPEGRecorder) Start(width, height int) error {
 	if r.recording {
 		return nil // Already recording
 	}
 
-	// Create AVI writer
-	writer, err := mjpeg.New(r.outputPath, int32(width), int32(height), r.fps)
-	if err != nil {
+	r.segment = 0
+	if err := r.openWriter(width, height); err != nil {
 		return err
 	}
+	if r.audio != nil && r.audioMode == AudioWAV {
+		var err error
+		if r.wav, err = audio.NewWAVWriter(wavPath(r.outputPath), r.audio.SampleRate()); err != nil {
+			r.writer.Close()
+			return err
+		}
+	}
+	r.audioFrames = 0
 
-	r.writer = writer
-	r.width = int32(width)
-	r.height = int32(height)
 	r.recording = true
 	r.frameCount = 0
+	r.stats.reset()
+
+	r.logger.Info("recording started", LogKeyPath, r.outputPath, "width", r.width, "height", r.height, "fps", r.fps)
+	return nil
+}
+
+// openWriter creates the AVI for the current segment
+// width, height: the game's frame size
+func (r *MJPEGRecorder) openWriter(width, height int) error {
+	path := segmentPath(r.outputPath, r.segment)
+
+	// The AVI has the size of transformed frames
+	outW, outH := r.transform.t.OutputSize(width, height)
+
+	// icza/mjpeg has no audio track, so muxed audio uses our own writer
+	var writer mjpeg.AviWriter
+	var err error
+	r.aviAudio = nil
+	if r.audio != nil && r.audioMode == AudioMux {
+		r.aviAudio, err = encode.NewAVIWriter(path, outW, outH, int(r.fps), r.audio.SampleRate())
+		writer = r.aviAudio
+	} else {
+		writer, err = mjpeg.New(path, int32(outW), int32(outH), r.fps)
+	}
+	if err != nil {
+		return err
+	}
 
+	r.writer = writer
+	r.size.start(width, height)
+	r.width, r.height = int32(outW), int32(outH)
 	return nil
 }
 
+// nextSegment finishes the current AVI and continues in a new one for
+// frames of width x height
+// The WAV, if any, stays a single file covering all segments
+func (r *MJPEGRecorder) nextSegment(width, height int) error {
+	if err := r.writer.Close(); err != nil {
+		return err
+	}
+	r.logger.Info("segment saved", LogKeyPath, segmentPath(r.outputPath, r.segment), LogKeyFrames, r.frameCount)
+	r.segment++
+	return r.openWriter(width, height)
+}
+
 // Stop stops recording frames
 func (r *MJPEGRecorder) Stop() error {
 	if !r.recording {
@@ -76,15 +173,44 @@ func (r *MJPEGRecorder) Stop() error {
 	}
 
 	r.recording = false
+	r.stats.finish()
 
-	// Close and finalize the AVI file
+	// Close and finalize the AVI and WAV files, both even if one fails
+	var writerErr, wavErr error
 	if r.writer != nil {
-		return r.writer.Close()
+		writerErr = r.writer.Close()
+	}
+	if r.wav != nil {
+		if wavErr = r.wav.Close(); wavErr == nil {
+			r.logger.Info("audio saved", LogKeyPath, wavPath(r.outputPath))
+		}
+	}
+	if err := errors.Join(writerErr, wavErr); err != nil {
+		return err
 	}
 
+	r.stats.bytes = 0
+	for i := 0; i <= r.segment; i++ {
+		r.stats.bytes += fileSize(segmentPath(r.outputPath, i))
+	}
+	stats := r.Stats()
+	r.logger.Info("recording saved",
+		LogKeyPath, r.outputPath,
+		LogKeyFrames, r.frameCount,
+		"segments", r.segment+1,
+		LogKeyBytes, stats.BytesWritten,
+		LogKeyElapsed, stats.WallDuration)
+	if err := writeStatsSidecar(r.outputPath, stats); err != nil {
+		r.logger.Warn("failed to write stats", LogKeyPath, r.outputPath, "error", err)
+	}
 	return nil
 }
 
+// Stats returns a snapshot of the current or last recording's health
+func (r *MJPEGRecorder) Stats() Stats {
+	return r.stats.snapshot(int(r.fps))
+}
+
 // IsRecording returns true if currently recording
 func (r *MJPEGRecorder) IsRecording() bool {
 	return r.recording
@@ -95,6 +221,12 @@ func (r *MJPEGRecorder) FrameCount() int {
 	return r.frameCount
 }
 
+// repeatNext marks the next captured frame as a repeat of the last one,
+// made to keep pace with the wall clock, so it isn't counted as unchanged
+func (r *MJPEGRecorder) repeatNext() {
+	r.stats.repeatNext = true
+}
+
 // CaptureFrame captures the current screen frame
 // Call this from your game's Draw method
 func (r *MJPEGRecorder) CaptureFrame(screen *ebiten.Image) error {
@@ -104,6 +236,7 @@ func (r *MJPEGRecorder) CaptureFrame(screen *ebiten.Image) error {
 
 	// Check if we've hit the max frame limit
 	if r.maxFrames > 0 && r.frameCount >= r.maxFrames {
+		r.logger.Warn("frame limit reached, stopping", LogKeyPath, r.outputPath, LogKeyFrames, r.frameCount)
 		return r.Stop()
 	}
 
@@ -111,8 +244,10 @@ func (r *MJPEGRecorder) CaptureFrame(screen *ebiten.Image) error {
 	w, h := bounds.Dx(), bounds.Dy()
 
 	// Read pixels from the screen
+	captureStart := time.Now()
 	pixels := make([]byte, 4*w*h)
 	screen.ReadPixels(pixels)
+	captureTime := time.Since(captureStart)
 
 	// Convert to RGBA image
 	rgba := &image.RGBA{
@@ -120,22 +255,66 @@ func (r *MJPEGRecorder) CaptureFrame(screen *ebiten.Image) error {
 		Stride: 4 * w,
 		Rect:   bounds,
 	}
+	rgba, newSegment := r.size.fit(rgba, r.logger, r.outputPath)
+	if newSegment {
+		if err := r.nextSegment(w, h); err != nil {
+			r.dropFrame(err)
+			return err
+		}
+	}
 
 	// Encode frame as JPEG
-	var buf bytes.Buffer
-	if err := jpeg.Encode(&buf, rgba, &jpeg.Options{Quality: r.jpegQuality}); err != nil {
+	encodeStart := time.Now()
+	rgba = r.transform.apply(rgba)
+	frame, err := encode.JPEG(rgba, r.jpegQuality)
+	if err != nil {
+		r.dropFrame(err)
 		return err
 	}
 
 	// Add JPEG frame to AVI
-	if err := r.writer.AddFrame(buf.Bytes()); err != nil {
+	if err := r.writer.AddFrame(frame); err != nil {
+		r.dropFrame(err)
 		return err
 	}
+	r.stats.encodeTime += time.Since(encodeStart)
 
+	r.stats.frame(pixels, captureTime)
 	r.frameCount++
+
+	// A failed audio write leaves a gap in the sound, not in the video
+	if err := r.captureAudio(); err != nil {
+		r.logger.Warn("audio write failed", LogKeyPath, r.outputPath, LogKeyFrames, r.frameCount, "error", err)
+	}
 	return nil
 }
 
+// captureAudio writes the audio played during the frame just added
+// The track length follows the frame count, so the two can't drift apart
+func (r *MJPEGRecorder) captureAudio() error {
+	if r.audio == nil {
+		return nil
+	}
+	due := int64(r.frameCount)*int64(r.audio.SampleRate())/int64(r.fps) - r.audioFrames
+	pcm := r.audio.Mix(int(due))
+	r.audioFrames += due
+	if r.aviAudio != nil {
+		return r.aviAudio.AddAudio(pcm)
+	}
+	_, err := r.wav.Write(pcm)
+	return err
+}
+
+// dropFrame counts and logs a frame that couldn't be written
+func (r *MJPEGRecorder) dropFrame(err error) {
+	r.stats.dropped++
+	r.logger.Warn("frame dropped",
+		LogKeyPath, r.outputPath,
+		LogKeyFrames, r.frameCount,
+		LogKeyElapsed, time.Since(r.stats.start),
+		"error", err)
+}
+
 // GetOutputPath returns the configured output path
 func (r *MJPEGRecorder) GetOutputPath() string {
 	return r.outputPath
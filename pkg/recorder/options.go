@@ -0,0 +1,32 @@
+package recorder
+
+import "log/slog"
+
+// Option configures a GameWrapper
+type Option func(*GameWrapper)
+
+// OnComplete registers a callback run once when the wrapper finishes,
+// either because auto-recording reached its duration or the
+// save-and-quit hotkey was pressed
+// err is nil if the recording (if any) was saved successfully
+// The callback runs before Update returns, so it must not block
+func OnComplete(fn func(err error)) Option {
+	return func(w *GameWrapper) {
+		w.onComplete = fn
+	}
+}
+
+// WithLogger sets the logger used by the wrapper and its recorder
+// Every record carries the game name; see WithGameName
+func WithLogger(logger *slog.Logger) Option {
+	return func(w *GameWrapper) {
+		w.logger = logger
+	}
+}
+
+// WithGameName sets the game attribute attached to every log record
+func WithGameName(name string) Option {
+	return func(w *GameWrapper) {
+		w.gameName = name
+	}
+}
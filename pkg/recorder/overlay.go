@@ -0,0 +1,157 @@
+package recorder
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	input "github.com/quasilyte/ebitengine-input"
+)
+
+// videoLayer is drawn on top of recorded frames but not the live screen
+type videoLayer interface {
+	update()
+	draw(dst *ebiten.Image)
+}
+
+// Overlay timing and layout, in ticks and screen pixels
+const (
+	rippleTicks  = 24
+	rippleRadius = 28
+	touchRadius  = 18
+	overlayLineH = 18
+	overlayCharW = 6
+	overlayPad   = 6
+)
+
+// Not premultiplied, so a ripple fades by lowering its alpha alone
+var (
+	overlayBackground = color.NRGBA{0, 0, 0, 160}
+	overlayRipple     = color.NRGBA{255, 220, 64, 255}
+	overlayTouch      = color.NRGBA{255, 255, 255, 96}
+)
+
+// InputOverlay draws the active input actions with their bound keys,
+// mouse click ripples and touch points on recorded frames
+// Register the game's handlers and the actions to show, then pass it to
+// WrapGame with WithInputOverlay
+type InputOverlay struct {
+	handlers []*input.Handler
+	actions  []overlayAction
+	ripples  []ripple
+	touches  []ebiten.TouchID
+}
+
+type overlayAction struct {
+	action input.Action
+	label  string
+}
+
+// ripple is an expanding circle marking a click or tap
+type ripple struct {
+	x, y float32
+	age  int
+}
+
+// NewInputOverlay creates an overlay with no handlers or actions
+// Clicks and touches are shown even without any
+func NewInputOverlay() *InputOverlay {
+	return &InputOverlay{}
+}
+
+// AddHandler shows the actions of h
+// Handlers can be added at any time, e.g. when the game creates them
+func (o *InputOverlay) AddHandler(h *input.Handler) {
+	o.handlers = append(o.handlers, h)
+}
+
+// AddAction shows action as label while it's pressed
+// Actions are listed in the order they were added
+func (o *InputOverlay) AddAction(action input.Action, label string) {
+	o.actions = append(o.actions, overlayAction{action: action, label: label})
+}
+
+// WithInputOverlay burns o into the recorded video
+// The live screen stays clean
+func WithInputOverlay(o *InputOverlay) Option {
+	return func(w *GameWrapper) {
+		w.layers = append(w.layers, o)
+	}
+}
+
+func (o *InputOverlay) update() {
+	kept := o.ripples[:0]
+	for _, r := range o.ripples {
+		if r.age++; r.age < rippleTicks {
+			kept = append(kept, r)
+		}
+	}
+	o.ripples = kept
+
+	for _, b := range []ebiten.MouseButton{ebiten.MouseButtonLeft, ebiten.MouseButtonRight, ebiten.MouseButtonMiddle} {
+		if inpututil.IsMouseButtonJustPressed(b) {
+			x, y := ebiten.CursorPosition()
+			o.ripples = append(o.ripples, ripple{x: float32(x), y: float32(y)})
+		}
+	}
+	for _, id := range inpututil.AppendJustPressedTouchIDs(nil) {
+		x, y := ebiten.TouchPosition(id)
+		o.ripples = append(o.ripples, ripple{x: float32(x), y: float32(y)})
+	}
+	o.touches = ebiten.AppendTouchIDs(o.touches[:0])
+}
+
+func (o *InputOverlay) draw(dst *ebiten.Image) {
+	for _, id := range o.touches {
+		x, y := ebiten.TouchPosition(id)
+		vector.FillCircle(dst, float32(x), float32(y), touchRadius, overlayTouch, true)
+	}
+	for _, r := range o.ripples {
+		t := float32(r.age) / rippleTicks
+		clr := overlayRipple
+		clr.A = uint8(255 * (1 - t))
+		vector.StrokeCircle(dst, r.x, r.y, 4+rippleRadius*t, 3, clr, true)
+	}
+
+	lines := o.activeLines()
+	if len(lines) == 0 {
+		return
+	}
+	width := 0
+	for _, line := range lines {
+		width = max(width, len(line)*overlayCharW)
+	}
+	height := len(lines) * overlayLineH
+	x := overlayPad
+	y := dst.Bounds().Dy() - height - 2*overlayPad
+	vector.FillRect(dst, float32(x), float32(y), float32(width+2*overlayPad), float32(height+overlayPad), overlayBackground, false)
+	for i, line := range lines {
+		ebitenutil.DebugPrintAt(dst, line, x+overlayPad, y+overlayPad/2+i*overlayLineH)
+	}
+}
+
+// activeLines lists the pressed actions as "label [key names]"
+// With several handlers each line is prefixed by the player number
+func (o *InputOverlay) activeLines() []string {
+	var lines []string
+	for i, h := range o.handlers {
+		for _, a := range o.actions {
+			if !h.ActionIsPressed(a.action) {
+				continue
+			}
+			line := a.label
+			if names := h.ActionKeyNames(a.action, h.DefaultInputMask()); len(names) > 0 {
+				line += " [" + strings.Join(names, " / ") + "]"
+			}
+			if len(o.handlers) > 1 {
+				line = fmt.Sprintf("P%d %s", i+1, line)
+			}
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,133 @@
+package recorder
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+// ResizePolicy decides what a recorder does when the game's frame size
+// changes mid-recording, e.g. after a window resize
+type ResizePolicy int
+
+const (
+	ResizeScale     ResizePolicy = iota // scale frames to the size the recording started at
+	ResizeLetterbox                     // fit frames into the starting size, keeping their aspect ratio
+	ResizeSegment                       // finish the file and continue in <name>-partN at the new size
+)
+
+func (p ResizePolicy) String() string {
+	switch p {
+	case ResizeScale:
+		return "scale"
+	case ResizeLetterbox:
+		return "letterbox"
+	case ResizeSegment:
+		return "segment"
+	}
+	return fmt.Sprintf("ResizePolicy(%d)", int(p))
+}
+
+// ParseResizePolicy parses "scale", "letterbox" or "segment"
+func ParseResizePolicy(s string) (ResizePolicy, error) {
+	for _, p := range []ResizePolicy{ResizeScale, ResizeLetterbox, ResizeSegment} {
+		if strings.EqualFold(s, p.String()) {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("recorder: unknown resize policy %q", s)
+}
+
+// WithResizePolicy sets what happens when the game's size changes
+// Defaults to RECORD_RESIZE, or ResizeScale
+func WithResizePolicy(p ResizePolicy) Option {
+	return func(w *GameWrapper) {
+		w.recorder.SetResizePolicy(p)
+	}
+}
+
+// defaultResizePolicy reads RECORD_RESIZE
+func defaultResizePolicy() (ResizePolicy, error) {
+	if s := os.Getenv("RECORD_RESIZE"); s != "" {
+		return ParseResizePolicy(s)
+	}
+	return ResizeScale, nil
+}
+
+// segmentPath returns the path of segment n (0-based) of a recording
+// The first segment keeps the original path
+func segmentPath(path string, n int) string {
+	if n == 0 {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s-part%d%s", strings.TrimSuffix(path, ext), n+1, ext)
+}
+
+// frameResizer brings frames of a new size back to the recording's size
+type frameResizer struct {
+	dst *image.RGBA
+}
+
+// apply returns src scaled, or letterboxed, to width x height
+// The result is only valid until the next call
+func (f *frameResizer) apply(src *image.RGBA, width, height int, letterbox bool) *image.RGBA {
+	out := image.Rect(0, 0, width, height)
+	if f.dst == nil || f.dst.Bounds() != out {
+		f.dst = image.NewRGBA(out)
+	}
+	content := out
+	if letterbox {
+		_, content = Transform{Width: width, Height: height}.layout(src.Bounds())
+		draw.Draw(f.dst, out, image.NewUniform(color.Black), image.Point{}, draw.Src)
+	}
+	draw.CatmullRom.Scale(f.dst, content, src, src.Bounds(), draw.Src, nil)
+	return f.dst
+}
+
+// sizeGuard applies a ResizePolicy to the frames of one recording
+type sizeGuard struct {
+	policy        ResizePolicy
+	resizer       frameResizer
+	width, height int // size the recording (or segment) started at
+	lastW, lastH  int // size of the previous frame
+}
+
+// start sets the size frames are expected to have
+// 0x0 takes the size of the next frame
+func (g *sizeGuard) start(width, height int) {
+	g.width, g.height = width, height
+	g.lastW, g.lastH = width, height
+}
+
+// fit returns rgba at the expected size
+// newSegment is true when the policy is ResizeSegment and the size
+// changed; rgba is then returned as is, and the caller should start a
+// segment at its size (see start)
+func (g *sizeGuard) fit(rgba *image.RGBA, logger *slog.Logger, path string) (out *image.RGBA, newSegment bool) {
+	w, h := rgba.Bounds().Dx(), rgba.Bounds().Dy()
+	if g.width == 0 && g.height == 0 {
+		g.start(w, h)
+	}
+	changed := w != g.lastW || h != g.lastH
+	g.lastW, g.lastH = w, h
+	if w == g.width && h == g.height {
+		return rgba, false
+	}
+	if changed {
+		logger.Warn("frame size changed", LogKeyPath, path,
+			"from", fmt.Sprintf("%dx%d", g.width, g.height),
+			"to", fmt.Sprintf("%dx%d", w, h),
+			"policy", g.policy)
+	}
+	if g.policy == ResizeSegment {
+		return rgba, true
+	}
+	return g.resizer.apply(rgba, g.width, g.height, g.policy == ResizeLetterbox), false
+}
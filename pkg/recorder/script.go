@@ -0,0 +1,135 @@
+package recorder
+
+import (
+	"errors"
+	"main/pkg/bot"
+	"os"
+	"sync"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// WithInputScript plays script through synthetic OS input while the
+// game runs, so unattended recordings show actual gameplay
+// The input goes to whichever window has focus, so the game must keep it
+// and shouldn't share the desktop with other games while recording
+// Defaults to the script at RECORD_INPUT_SCRIPT, if set
+func WithInputScript(script *bot.Script) Option {
+	return func(w *GameWrapper) {
+		w.script = script
+	}
+}
+
+// defaultInputScript loads the script named by RECORD_INPUT_SCRIPT
+func defaultInputScript() (*bot.Script, error) {
+	path := os.Getenv("RECORD_INPUT_SCRIPT")
+	if path == "" {
+		return nil, nil
+	}
+	return bot.LoadScript(path)
+}
+
+// startBot creates the bot for the configured script
+// A missing injector only disables the bot; the game still records
+func (w *GameWrapper) startBot() {
+	if w.script == nil {
+		script, err := defaultInputScript()
+		if err != nil {
+			w.logger.Error("input script failed to load", "error", err)
+			return
+		}
+		w.script = script
+	}
+	if w.script == nil {
+		return
+	}
+	inj, err := bot.NewInjector()
+	if err != nil {
+		w.logger.Warn("input script disabled", "error", err)
+		return
+	}
+	w.bot = bot.New(w.script, inj)
+	scripted.add(w)
+	w.logger.Info("input script enabled", "steps", len(w.script.Steps))
+}
+
+// updateBot injects this tick's scripted input
+// Injected events reach the game through the OS on a following tick
+func (w *GameWrapper) updateBot() {
+	if w.bot == nil {
+		return
+	}
+	lw, lh := w.game.Layout(ebiten.WindowSize())
+	if err := w.bot.Update(bot.WindowViewport(lw, lh), lw, lh); err != nil && err.Error() != w.botErr {
+		// Log each distinct error once rather than every tick
+		w.botErr = err.Error()
+		w.logger.Warn("input script error", "error", err)
+	}
+}
+
+// stopBot releases any input the bot is holding
+func (w *GameWrapper) stopBot() error {
+	if w.bot == nil {
+		return nil
+	}
+	err := w.bot.Close()
+	if err != nil {
+		w.logger.Warn("input script close failed", "error", err)
+	}
+	w.bot = nil
+	scripted.remove(w)
+	return err
+}
+
+// Close releases any OS input the wrapper's input script is holding
+// The wrapper releases it itself when it ends the game, but RunGame can
+// also return without it, e.g. when the window is closed, and injected
+// keys stay pressed after the game exits; so the host must call Close
+// once RunGame returns. It's safe to call more than once
+func (w *GameWrapper) Close() error {
+	return w.stopBot()
+}
+
+// CloseAll closes every wrapper still running an input script and
+// returns err, so it can enclose a RunGame call:
+//
+//	err := recorder.CloseAll(ebiten.RunGame(recorder.WrapGame(game, ...)))
+//
+// This is how games patched by pkg/gamepatch release their input
+func CloseAll(err error) error {
+	for _, w := range scripted.all() {
+		err = errors.Join(err, w.Close())
+	}
+	return err
+}
+
+// scripted tracks the wrappers with a running bot, for CloseAll
+var scripted wrapperSet
+
+type wrapperSet struct {
+	mu       sync.Mutex
+	wrappers []*GameWrapper
+}
+
+func (s *wrapperSet) add(w *GameWrapper) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.wrappers = append(s.wrappers, w)
+}
+
+func (s *wrapperSet) remove(w *GameWrapper) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, x := range s.wrappers {
+		if x == w {
+			s.wrappers = append(s.wrappers[:i], s.wrappers[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *wrapperSet) all() []*GameWrapper {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*GameWrapper(nil), s.wrappers...)
+}
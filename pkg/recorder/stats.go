@@ -0,0 +1,155 @@
+package recorder
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/maphash"
+	"os"
+	"slices"
+	"time"
+)
+
+// Stats is a snapshot of a recording's health
+// Durations are serialized as nanoseconds
+type Stats struct {
+	FramesCaptured   int           `json:"frames_captured"`
+	FramesDropped    int           `json:"frames_dropped"`
+	FramesDuplicated int           `json:"frames_duplicated"` // identical to the previous frame
+	FramesRepeated   int           `json:"frames_repeated"`   // a slow frame captured again to keep pace with the wall clock
+	AvgCaptureTime   time.Duration `json:"avg_capture_time_ns"`
+	P99CaptureTime   time.Duration `json:"p99_capture_time_ns"`
+	EncodeTime       time.Duration `json:"encode_time_ns"`
+	BytesWritten     int64         `json:"bytes_written"`
+	TargetFPS        int           `json:"target_fps"`
+	EffectiveFPS     float64       `json:"effective_fps"`
+	WallDuration     time.Duration `json:"wall_duration_ns"`
+}
+
+// Problems lists reasons the recording looks broken or stuttered
+// An empty result means the recording looks healthy
+func (s Stats) Problems() []string {
+	var problems []string
+	if s.FramesCaptured == 0 {
+		problems = append(problems, "no frames captured")
+		return problems
+	}
+	if s.FramesDropped > 0 {
+		problems = append(problems, fmt.Sprintf("%d frames dropped", s.FramesDropped))
+	}
+	// A mostly frozen picture usually means a title screen or a hang
+	// Repeats are the same frame by design, so only distinct captures count
+	if distinct := s.FramesCaptured - s.FramesRepeated; s.FramesDuplicated*2 > distinct {
+		problems = append(problems, fmt.Sprintf("%d of %d frames unchanged", s.FramesDuplicated, distinct))
+	}
+	if s.TargetFPS > 0 {
+		if s.EffectiveFPS < float64(s.TargetFPS)*0.8 {
+			problems = append(problems, fmt.Sprintf("effective fps %.1f below target %d", s.EffectiveFPS, s.TargetFPS))
+		}
+		if s.P99CaptureTime > time.Second/time.Duration(s.TargetFPS) {
+			problems = append(problems, fmt.Sprintf("p99 capture time %s exceeds frame interval", s.P99CaptureTime))
+		}
+	}
+	return problems
+}
+
+// statsCollector accumulates the measurements behind Stats
+type statsCollector struct {
+	seed         maphash.Seed
+	captureTimes []time.Duration
+	encodeTime   time.Duration
+	captured     int
+	dropped      int
+	duplicated   int
+	repeated     int
+	repeatNext   bool // the next frame is a pacing repeat
+	lastHash     uint64
+	start        time.Time
+	stop         time.Time
+	bytes        int64
+}
+
+// reset clears all measurements and marks the start of a recording
+func (c *statsCollector) reset() {
+	if c.seed == (maphash.Seed{}) {
+		c.seed = maphash.MakeSeed()
+	}
+	*c = statsCollector{seed: c.seed, captureTimes: c.captureTimes[:0], start: time.Now()}
+}
+
+// frame records a captured frame's pixels and how long reading them took
+// A pacing repeat is counted as such and not compared with the last frame
+func (c *statsCollector) frame(pix []byte, captureTime time.Duration) {
+	c.captured++
+	c.captureTimes = append(c.captureTimes, captureTime)
+	if c.repeatNext {
+		c.repeatNext = false
+		c.repeated++
+		return
+	}
+	h := maphash.Bytes(c.seed, pix)
+	if c.captured-c.repeated > 1 && h == c.lastHash {
+		c.duplicated++
+	}
+	c.lastHash = h
+}
+
+// finish marks the end of a recording
+func (c *statsCollector) finish() {
+	c.stop = time.Now()
+}
+
+// snapshot returns the current Stats
+func (c *statsCollector) snapshot(fps int) Stats {
+	s := Stats{
+		FramesCaptured:   c.captured,
+		FramesDropped:    c.dropped,
+		FramesDuplicated: c.duplicated,
+		FramesRepeated:   c.repeated,
+		EncodeTime:       c.encodeTime,
+		BytesWritten:     c.bytes,
+		TargetFPS:        fps,
+	}
+
+	if !c.start.IsZero() {
+		end := c.stop
+		if end.IsZero() {
+			end = time.Now()
+		}
+		s.WallDuration = end.Sub(c.start)
+	}
+	if s.WallDuration > 0 {
+		s.EffectiveFPS = float64(c.captured) / s.WallDuration.Seconds()
+	}
+
+	if n := len(c.captureTimes); n > 0 {
+		var total time.Duration
+		for _, d := range c.captureTimes {
+			total += d
+		}
+		s.AvgCaptureTime = total / time.Duration(n)
+
+		sorted := slices.Clone(c.captureTimes)
+		slices.Sort(sorted)
+		s.P99CaptureTime = sorted[(n*99+99)/100-1]
+	}
+
+	return s
+}
+
+// statsSidecarPath returns where the stats of outputPath are written
+func statsSidecarPath(outputPath string) string {
+	return outputPath + ".stats.json"
+}
+
+// writeStatsSidecar writes s, and any problems found, next to outputPath
+func writeStatsSidecar(outputPath string, s Stats) error {
+	data, err := json.MarshalIndent(struct {
+		Path string `json:"path"`
+		Stats
+		Problems []string `json:"problems,omitempty"`
+	}{outputPath, s, s.Problems()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(statsSidecarPath(outputPath), data, 0644)
+}
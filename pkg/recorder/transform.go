@@ -0,0 +1,159 @@
+package recorder
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"main/pkg/settings"
+	"os"
+
+	"golang.org/x/image/draw"
+)
+
+// Transform maps captured frames to the output video size
+// Steps run in order: crop, integer upscale, then fit into Width x Height
+// Integer scale factors use nearest-neighbour, so pixel art stays sharp;
+// any other factor uses Catmull-Rom, which also gives clean downscales
+type Transform struct {
+	// Crop is the region of interest in game pixels; empty keeps the
+	// whole frame. It must lie inside the frame when recording starts
+	Crop image.Rectangle
+
+	// Scale upscales by a whole factor with nearest-neighbour (0 or 1 = none)
+	Scale int
+
+	// Width and Height fit the frame into a fixed output size, keeping
+	// its aspect ratio; the rest is filled with Background, which
+	// letterboxes or pillarboxes it (0 = no fitting)
+	Width, Height int
+
+	// PixelArt limits fitting to whole scale factors when upscaling, so
+	// every game pixel becomes the same number of output pixels
+	PixelArt bool
+
+	// Background fills the bars; the zero value is black
+	Background color.RGBA
+}
+
+// Output size presets, e.g. Transform{Width: Preset720p.X, Height: Preset720p.Y}
+var (
+	Preset720p  = settings.Preset720p
+	Preset1080p = settings.Preset1080p
+)
+
+// Fit returns a transform that fits frames into size, as with the presets
+func Fit(size image.Point) Transform {
+	return Transform{Width: size.X, Height: size.Y}
+}
+
+// WithTransform applies t to every recorded frame
+// Defaults to RECORD_SIZE ("720p", "1080p" or "WIDTHxHEIGHT"), with
+// whole-factor upscaling if RECORD_PIXEL_ART is set
+func WithTransform(t Transform) Option {
+	return func(w *GameWrapper) {
+		w.transform = t
+		w.recorder.SetTransform(t)
+	}
+}
+
+// defaultTransform reads RECORD_SIZE and RECORD_PIXEL_ART
+func defaultTransform() (Transform, error) {
+	var t Transform
+	if size := os.Getenv("RECORD_SIZE"); size != "" {
+		p, err := ParseSize(size)
+		if err != nil {
+			return t, err
+		}
+		t = Fit(p)
+	}
+	t.PixelArt = os.Getenv("RECORD_PIXEL_ART") != ""
+	return t, nil
+}
+
+// ParseSize parses "720p", "1080p" or "WIDTHxHEIGHT"
+func ParseSize(s string) (image.Point, error) {
+	p, err := settings.ParseSize(s)
+	if err != nil {
+		return p, fmt.Errorf("recorder: %w", err)
+	}
+	return p, nil
+}
+
+// IsIdentity reports whether t leaves frames unchanged
+func (t Transform) IsIdentity() bool {
+	return t.Crop.Empty() && t.Scale <= 1 && (t.Width <= 0 || t.Height <= 0)
+}
+
+// check reports whether t's crop lies inside a width x height frame
+func (t Transform) check(width, height int) error {
+	if !t.Crop.Empty() && !t.Crop.In(image.Rect(0, 0, width, height)) {
+		return fmt.Errorf("crop %v is outside the %dx%d frame", t.Crop, width, height)
+	}
+	return nil
+}
+
+// OutputSize returns the size of a width x height frame after t
+func (t Transform) OutputSize(width, height int) (int, int) {
+	r, _ := t.layout(image.Rect(0, 0, width, height))
+	return r.Dx(), r.Dy()
+}
+
+// layout returns the output bounds and where the source region lands
+func (t Transform) layout(src image.Rectangle) (out, content image.Rectangle) {
+	crop := t.cropRect(src)
+	scale := max(t.Scale, 1)
+	cw, ch := crop.Dx()*scale, crop.Dy()*scale
+	if t.Width <= 0 || t.Height <= 0 || cw == 0 || ch == 0 {
+		r := image.Rect(0, 0, cw, ch)
+		return r, r
+	}
+
+	s := min(float64(t.Width)/float64(cw), float64(t.Height)/float64(ch))
+	if t.PixelArt && s >= 1 {
+		s = float64(int(s))
+	}
+	dw, dh := int(float64(cw)*s+0.5), int(float64(ch)*s+0.5)
+	x, y := (t.Width-dw)/2, (t.Height-dh)/2
+	return image.Rect(0, 0, t.Width, t.Height), image.Rect(x, y, x+dw, y+dh)
+}
+
+func (t Transform) cropRect(src image.Rectangle) image.Rectangle {
+	if t.Crop.Empty() {
+		return src
+	}
+	return t.Crop.Add(src.Min).Intersect(src)
+}
+
+// frameTransform applies a Transform, reusing its output buffer
+type frameTransform struct {
+	t   Transform
+	dst *image.RGBA
+}
+
+// apply returns src transformed
+// The result is only valid until the next call
+func (f *frameTransform) apply(src *image.RGBA) *image.RGBA {
+	crop := f.t.cropRect(src.Bounds())
+	if f.t.IsIdentity() || crop.Empty() {
+		return src
+	}
+	out, content := f.t.layout(src.Bounds())
+	if f.dst == nil || f.dst.Bounds() != out {
+		f.dst = image.NewRGBA(out)
+	}
+	if content != out {
+		bg := f.t.Background
+		if bg == (color.RGBA{}) {
+			bg = color.RGBA{A: 255}
+		}
+		draw.Draw(f.dst, out, image.NewUniform(bg), image.Point{}, draw.Src)
+	}
+
+	var scaler draw.Scaler = draw.CatmullRom
+	if content.Dx()%crop.Dx() == 0 && content.Dy()%crop.Dy() == 0 &&
+		content.Dx()/crop.Dx() == content.Dy()/crop.Dy() {
+		scaler = draw.NearestNeighbor
+	}
+	scaler.Scale(f.dst, content, src, crop, draw.Src, nil)
+	return f.dst
+}
@@ -0,0 +1,114 @@
+package recorder
+
+import (
+	"image"
+	"image/color"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// Corner places a watermark
+type Corner int
+
+const (
+	CornerBottomRight Corner = iota
+	CornerBottomLeft
+	CornerTopRight
+	CornerTopLeft
+)
+
+// Watermark is text or an image drawn in a corner of every recorded
+// frame, title cards included
+type Watermark struct {
+	// Text is drawn if Image is nil
+	Text string
+
+	// Image is drawn at its own size
+	Image image.Image
+
+	Corner Corner
+
+	// Opacity is 0 to 1 (0 = 0.6)
+	Opacity float64
+}
+
+// WithWatermark burns wm into the recorded video
+// The live screen stays clean
+// Defaults to RECORD_WATERMARK, a path ending in .png or any text
+func WithWatermark(wm Watermark) Option {
+	return func(w *GameWrapper) {
+		w.watermark = &watermarkLayer{wm: wm}
+	}
+}
+
+// parseWatermark reads a watermark from an environment value
+func parseWatermark(s string) (Watermark, error) {
+	if strings.HasSuffix(strings.ToLower(s), ".png") {
+		c, err := ImageCard(s)
+		return Watermark{Image: c.Image}, err
+	}
+	return Watermark{Text: s}, nil
+}
+
+// watermarkLayer draws a Watermark, rendering it once per frame height
+type watermarkLayer struct {
+	wm     Watermark
+	img    *ebiten.Image
+	height int
+}
+
+func (l *watermarkLayer) update() {}
+
+func (l *watermarkLayer) draw(dst *ebiten.Image) {
+	b := dst.Bounds()
+	if l.img == nil || l.height != b.Dy() {
+		if l.img != nil {
+			l.img.Deallocate()
+		}
+		l.img = ebiten.NewImageFromImage(l.render(b.Dy()))
+		l.height = b.Dy()
+	}
+
+	// The margin follows the frame size, like the text
+	margin := max(b.Dy()/40, 4)
+	w, h := l.img.Bounds().Dx(), l.img.Bounds().Dy()
+	x, y := b.Max.X-w-margin, b.Max.Y-h-margin
+	if l.wm.Corner == CornerBottomLeft || l.wm.Corner == CornerTopLeft {
+		x = b.Min.X + margin
+	}
+	if l.wm.Corner == CornerTopRight || l.wm.Corner == CornerTopLeft {
+		y = b.Min.Y + margin
+	}
+
+	opacity := l.wm.Opacity
+	if opacity <= 0 {
+		opacity = 0.6
+	}
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(float64(x), float64(y))
+	op.ColorScale.ScaleAlpha(float32(min(opacity, 1)))
+	dst.DrawImage(l.img, op)
+}
+
+// render returns the watermark image for frames of the given height
+// Text gets a drop shadow so it reads on light and dark games alike
+func (l *watermarkLayer) render(height int) image.Image {
+	if l.wm.Image != nil {
+		return l.wm.Image
+	}
+	face := cardFace(height / 24)
+	width := font.MeasureString(face, l.wm.Text).Ceil()
+	ascent := face.Metrics().Ascent.Ceil()
+	img := image.NewRGBA(image.Rect(0, 0, width+1, face.Metrics().Height.Ceil()+1))
+	for _, p := range []struct {
+		off int
+		clr color.Color
+	}{{1, color.Black}, {0, color.White}} {
+		d := font.Drawer{Dst: img, Src: image.NewUniform(p.clr), Face: face, Dot: fixed.P(p.off, ascent+p.off)}
+		d.DrawString(l.wm.Text)
+	}
+	return img
+}
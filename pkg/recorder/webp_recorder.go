@@ -3,10 +3,11 @@ package recorder
 import (
 	"image"
 	"image/color/palette"
-	"image/draw"
+	"log/slog"
+	"main/pkg/recorder/encode"
 	"os"
+	"time"
 
-	"github.com/HugoSmits86/nativewebp"
 	"github.com/hajimehoshi/ebiten/v2"
 )
 
@@ -20,6 +21,11 @@ type WebPRecorder struct {
 	frameCount  int
 	outputPath  string
 	frameDelay  int // delay in milliseconds
+	stats       statsCollector
+	transform   frameTransform
+	size        sizeGuard
+	segment     int
+	logger      *slog.Logger
 }
 
 // NewWebPRecorder creates a new WebP recorder (pure Go, no CGO/ffmpeg)
@@ -43,21 +49,53 @@ func NewWebPRecorder(maxFrames, fps int, outputPath string) *WebPRecorder {
 		fps:        fps,
 		outputPath: outputPath,
 		frameDelay: frameDelay,
+		logger:     defaultLogger(),
 	}
 }
 
+// SetLogger sets the logger used for start, save and dropped-frame records
+func (r *WebPRecorder) SetLogger(logger *slog.Logger) {
+	r.logger = logger
+}
+
+// SetTransform crops, scales and letterboxes frames before encoding
+// Call it before Start
+func (r *WebPRecorder) SetTransform(t Transform) {
+	r.transform = frameTransform{t: t}
+}
+
+// SetResizePolicy sets what happens when the frame size changes
+// mid-recording; the default is ResizeScale
+func (r *WebPRecorder) SetResizePolicy(p ResizePolicy) {
+	r.size.policy = p
+}
+
 // Start begins recording frames
 func (r *WebPRecorder) Start() {
 	r.recording = true
 	r.frames = r.frames[:0]
 	r.frameCount = 0
+	r.segment = 0
+	r.size.start(0, 0)
+	r.stats.reset()
+
+	r.logger.Info("recording started", LogKeyPath, r.outputPath, "fps", r.fps)
 }
 
 // Stop stops recording frames
 func (r *WebPRecorder) Stop() {
+	if r.recording {
+		r.stats.finish()
+	}
 	r.recording = false
 }
 
+// Stats returns a snapshot of the current or last recording's health
+// BytesWritten is filled in by SaveWebP
+func (r *WebPRecorder) Stats() Stats {
+	return r.stats.snapshot(r.fps)
+}
+
 // IsRecording returns true if currently recording
 func (r *WebPRecorder) IsRecording() bool {
 	return r.recording
@@ -68,6 +106,12 @@ func (r *WebPRecorder) FrameCount() int {
 	return r.frameCount
 }
 
+// repeatNext marks the next captured frame as a repeat of the last one,
+// made to keep pace with the wall clock, so it isn't counted as unchanged
+func (r *WebPRecorder) repeatNext() {
+	r.stats.repeatNext = true
+}
+
 // CaptureFrame captures the current screen frame
 // Call this from your game's Draw method
 func (r *WebPRecorder) CaptureFrame(screen *ebiten.Image) {
@@ -77,6 +121,7 @@ func (r *WebPRecorder) CaptureFrame(screen *ebiten.Image) {
 
 	// Check if we've hit the max frame limit
 	if r.maxFrames > 0 && r.frameCount >= r.maxFrames {
+		r.logger.Warn("frame limit reached, stopping", LogKeyPath, r.outputPath, LogKeyFrames, r.frameCount)
 		r.Stop()
 		return
 	}
@@ -85,8 +130,10 @@ func (r *WebPRecorder) CaptureFrame(screen *ebiten.Image) {
 	w, h := bounds.Dx(), bounds.Dy()
 
 	// Read pixels from the screen
+	captureStart := time.Now()
 	pixels := make([]byte, 4*w*h)
 	screen.ReadPixels(pixels)
+	captureTime := time.Since(captureStart)
 
 	// Convert to RGBA image
 	rgba := &image.RGBA{
@@ -94,24 +141,49 @@ func (r *WebPRecorder) CaptureFrame(screen *ebiten.Image) {
 		Stride: 4 * w,
 		Rect:   bounds,
 	}
+	rgba, newSegment := r.size.fit(rgba, r.logger, r.outputPath)
+	if newSegment {
+		r.nextSegment()
+	}
 
 	// Convert to paletted image for WebP encoding
 	// Using Plan9 palette which provides good color representation
-	paletted := image.NewPaletted(bounds, palette.Plan9)
-	draw.Draw(paletted, bounds, rgba, bounds.Min, draw.Src)
+	encodeStart := time.Now()
+	rgba = r.transform.apply(rgba)
+	paletted := encode.Paletted(rgba, palette.Plan9, false)
+	r.stats.encodeTime += time.Since(encodeStart)
 
 	r.frames = append(r.frames, paletted)
+	r.stats.frame(pixels, captureTime)
 	r.frameCount++
 }
 
 // SaveWebP saves the recorded frames as an animated WebP file
 func (r *WebPRecorder) SaveWebP() error {
+	return r.saveTo(segmentPath(r.outputPath, r.segment))
+}
+
+// nextSegment saves the frames so far and continues in a new segment
+// at the size of the next frame
+func (r *WebPRecorder) nextSegment() {
+	path := segmentPath(r.outputPath, r.segment)
+	if err := r.saveTo(path); err != nil {
+		r.logger.Warn("failed to save segment", LogKeyPath, path, "error", err)
+	}
+	r.frames = r.frames[:0]
+	r.segment++
+	r.size.start(0, 0)
+}
+
+// saveTo writes the frames of the current segment to path
+func (r *WebPRecorder) saveTo(path string) error {
 	if len(r.frames) == 0 {
 		return nil // Nothing to save
 	}
 
 	// Create output file
-	f, err := os.Create(r.outputPath)
+	encodeStart := time.Now()
+	f, err := os.Create(path)
 	if err != nil {
 		return err
 	}
@@ -123,27 +195,33 @@ func (r *WebPRecorder) SaveWebP() error {
 		durations[i] = uint(r.frameDelay)
 	}
 
-	// Prepare disposal methods - 0 = keep frame
-	disposals := make([]uint, len(r.frames))
-
 	// Convert paletted images to generic images for nativewebp
 	genericFrames := make([]image.Image, len(r.frames))
 	for i, frame := range r.frames {
 		genericFrames[i] = frame
 	}
 
-	// Create animation struct
-	animation := &nativewebp.Animation{
-		Images:          genericFrames,
-		Durations:       durations,
-		Disposals:       disposals,
-		LoopCount:       0, // 0 = infinite loop
-		BackgroundColor: 0x00000000, // transparent black
-	}
-
 	// Encode all frames as animated WebP
 	// Using lossless encoding for best quality
-	return nativewebp.EncodeAll(f, animation, nil)
+	if err := encode.WriteWebP(f, genericFrames, durations); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	r.stats.encodeTime += time.Since(encodeStart)
+
+	r.stats.bytes = fileSize(path)
+	stats := r.Stats()
+	r.logger.Info("recording saved",
+		LogKeyPath, path,
+		LogKeyFrames, r.frameCount,
+		LogKeyBytes, stats.BytesWritten,
+		LogKeyElapsed, stats.WallDuration)
+	if err := writeStatsSidecar(path, stats); err != nil {
+		r.logger.Warn("failed to write stats", LogKeyPath, path, "error", err)
+	}
+	return nil
 }
 
 // GetOutputPath returns the configured output path
@@ -2,140 +2,445 @@ package recorder
 
 import (
 	"fmt"
-	"log"
+	"image"
+	"image/png"
+	"log/slog"
+	"main/pkg/bot"
+	"main/pkg/recorder/audio"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	input "github.com/quasilyte/ebitengine-input"
 )
 
 // GameWrapper wraps any ebiten.Game to add recording capability
 // without modifying the original game code
 type GameWrapper struct {
 	game            ebiten.Game
-	recorder        *MJPEGRecorder
+	recorder        frameRecorder
+	transform       Transform
 	recording       bool
 	recordingStatus string
 	autoRecord      bool
 	autoStart       time.Time
+	recordStart     time.Time
 	autoDuration    time.Duration
 	hasStarted      bool
+
+	// Hotkeys
+	keymap           input.Keymap
+	inputSystem      input.System
+	inputHandler     *input.Handler
+	paused           bool
+	pausedAt         time.Time
+	screenshotQueued bool
+	screenshotCount  int
+
+	onComplete func(err error)
+	events     eventHooks
+
+	// Audio, handed to the recorder once the options are applied
+	audioTap  *audio.Tap
+	audioMode AudioMode
+
+	// Scripted input
+	script *bot.Script
+	bot    *bot.Bot
+	botErr string
+
+	// Video-only layers and the frame they're composited on
+	layers []videoLayer
+	frame  *ebiten.Image
+
+	// Title cards and watermark
+	intro     *Card
+	outro     *Card
+	watermark *watermarkLayer
+
+	logger   *slog.Logger
+	gameName string
 }
 
 // WrapGame wraps an existing ebiten.Game with recording capability
-// outputPath: where to save the recording
-// quality: JPEG quality (1-100, recommend 85)
+// outputPath: where to save the recording; a .gif or .webp extension
+// records that format, anything else an MJPEG AVI
+// quality: JPEG quality for AVI (1-100, recommend 85)
 // autoRecord: if true, starts recording immediately
 // autoDuration: how long to record before auto-stopping (0 = manual)
-func WrapGame(game ebiten.Game, outputPath string, quality int, autoRecord bool, autoDuration time.Duration) *GameWrapper {
-	return &GameWrapper{
+// opts: optional settings such as WithKeymap
+//
+// The wrapper never exits the process. When auto-recording finishes or
+// the save-and-quit hotkey is pressed, Update returns ebiten.Termination
+// (or a *RecordingError if saving failed), which ends ebiten.RunGame
+// Call Close once RunGame returns, so an input script never leaves keys
+// pressed
+func WrapGame(game ebiten.Game, outputPath string, quality int, autoRecord bool, autoDuration time.Duration, opts ...Option) *GameWrapper {
+	fps, fpsErr := defaultFPS()
+
+	// Auto-recordings may run past the recorders' default frame limit; the
+	// extra minute leaves room for title cards
+	maxFrames := 0
+	if autoRecord && autoDuration > 0 {
+		maxFrames = int((autoDuration + time.Minute).Seconds() * float64(fps))
+	}
+	w := &GameWrapper{
 		game:         game,
-		recorder:     NewMJPEGRecorder(0, 30, outputPath, quality),
+		recorder:     newFrameRecorder(outputPath, maxFrames, quality, fps),
 		autoRecord:   autoRecord,
 		autoDuration: autoDuration,
+		keymap:       DefaultKeymap(),
+		logger:       defaultLogger(),
+		gameName:     defaultGameName(),
+	}
+	var transformErr error
+	w.transform, transformErr = defaultTransform()
+	w.recorder.SetTransform(w.transform)
+	resize, resizeErr := defaultResizePolicy()
+	w.recorder.SetResizePolicy(resize)
+	for _, opt := range opts {
+		opt(w)
+	}
+	w.logger = w.logger.With(LogKeyGame, w.gameName)
+	w.recorder.SetLogger(w.logger)
+	if transformErr != nil {
+		w.logger.Warn("ignoring RECORD_SIZE", "error", transformErr)
+	}
+	if resizeErr != nil {
+		w.logger.Warn("ignoring RECORD_RESIZE", "error", resizeErr)
 	}
+	if fpsErr != nil {
+		w.logger.Warn("ignoring RECORD_FPS", "error", fpsErr)
+	}
+	w.setAudio()
+	if err := applyWindowSize(); err != nil {
+		w.logger.Warn("ignoring RECORD_WINDOW_SIZE", "error", err)
+	}
+	w.defaultCards()
+	if w.watermark != nil {
+		w.layers = append(w.layers, w.watermark) // on top of other layers
+	}
+
+	// The wrapper has its own input system so its hotkeys are
+	// independent of whatever input handling the game does
+	w.inputSystem.Init(input.SystemConfig{
+		DevicesEnabled: input.KeyboardDevice | input.GamepadDevice,
+	})
+	w.inputHandler = w.inputSystem.NewHandler(0, w.keymap)
+
+	w.startBot()
+
+	return w
 }
 
 // Update implements ebiten.Game.Update
 func (w *GameWrapper) Update() error {
+	// A panic ends the process with no chance to call Close
+	defer func() {
+		if r := recover(); r != nil {
+			w.stopBot()
+			panic(r)
+		}
+	}()
+
+	w.inputSystem.Update()
+
+	// Pause freezes both the game and the recording
+	if w.inputHandler.ActionIsJustPressed(ActionPause) {
+		w.paused = !w.paused
+		if w.paused {
+			w.pausedAt = time.Now()
+			w.logger.Info("paused")
+		} else {
+			// Time spent paused doesn't count towards autoDuration
+			// or the video's timeline
+			w.autoStart = w.autoStart.Add(time.Since(w.pausedAt))
+			w.recordStart = w.recordStart.Add(time.Since(w.pausedAt))
+			w.logger.Info("resumed")
+		}
+	}
+	if w.inputHandler.ActionIsJustPressed(ActionScreenshot) {
+		w.screenshotQueued = true
+	}
+	for _, l := range w.layers {
+		l.update()
+	}
+
 	// Call original game's Update
-	if err := w.game.Update(); err != nil {
-		return err
+	if !w.paused {
+		w.updateBot()
+		if err := w.game.Update(); err != nil {
+			w.stopBot()
+			return err
+		}
 	}
 
 	// Handle auto-record start (first frame only)
 	if w.autoRecord && !w.hasStarted {
-		// Get screen size from Layout
-		width, height := w.game.Layout(0, 0)
-		if err := w.recorder.Start(width, height); err != nil {
-			log.Printf("Failed to start auto-recording: %v", err)
-		} else {
-			w.recording = true
-			w.autoStart = time.Now()
-			w.hasStarted = true
-			log.Printf("Auto-recording started: %s", w.recorder.GetOutputPath())
+		if err := w.startRecording(); err != nil {
+			return w.finish(err)
 		}
+		w.autoStart = time.Now() // after the intro, which doesn't count
+		w.hasStarted = true
 	}
 
-	// Handle auto-record stop
-	if w.autoRecord && w.recording && w.autoDuration > 0 {
+	// Handle auto-record stop; the clock stands still while paused
+	if w.autoRecord && w.recording && w.autoDuration > 0 && !w.paused {
 		elapsed := time.Since(w.autoStart)
 		if elapsed >= w.autoDuration {
-			if err := w.recorder.Stop(); err != nil {
-				log.Printf("Failed to save recording: %v", err)
-				os.Exit(1)
-			}
-			log.Printf("Recording saved to: %s (%d frames)", w.recorder.GetOutputPath(), w.recorder.FrameCount())
-			os.Exit(0)
+			return w.finish(w.stopRecording())
 		}
 		w.recordingStatus = fmt.Sprintf("REC: %.1fs/%.1fs (%d frames)",
 			elapsed.Seconds(), w.autoDuration.Seconds(), w.recorder.FrameCount())
 	}
 
-	// Manual recording toggle with R key (only if not auto-recording)
-	if !w.autoRecord && inpututil.IsKeyJustPressed(ebiten.KeyR) {
+	// Manual recording toggle (only if not auto-recording)
+	if !w.autoRecord && w.inputHandler.ActionIsJustPressed(ActionRecordToggle) {
 		if w.recording {
-			// Stop recording
-			if err := w.recorder.Stop(); err != nil {
+			if err := w.stopRecording(); err != nil {
 				w.recordingStatus = fmt.Sprintf("ERROR: %v", err)
-				log.Printf("Failed to stop recording: %v", err)
 			} else {
-				w.recording = false
-				msg := fmt.Sprintf("Saved: %s (%d frames)", w.recorder.GetOutputPath(), w.recorder.FrameCount())
-				w.recordingStatus = msg
-				log.Println(msg)
+				w.recordingStatus = fmt.Sprintf("Saved: %s (%d frames)", w.recorder.GetOutputPath(), w.recorder.FrameCount())
 			}
 		} else {
-			// Start recording
-			width, height := w.game.Layout(0, 0)
-			if err := w.recorder.Start(width, height); err != nil {
+			if err := w.startRecording(); err != nil {
 				w.recordingStatus = fmt.Sprintf("ERROR: %v", err)
-				log.Printf("Failed to start recording: %v", err)
 			} else {
-				w.recording = true
-				w.recordingStatus = "RECORDING (Press R to stop)"
-				log.Println("Recording started (Press R to stop)")
+				w.recordingStatus = "RECORDING"
 			}
 		}
 	}
 
 	// Update status during manual recording
 	if !w.autoRecord && w.recording {
-		w.recordingStatus = fmt.Sprintf("REC: %d frames (Press R to stop)", w.recorder.FrameCount())
+		w.recordingStatus = fmt.Sprintf("REC: %d frames", w.recorder.FrameCount())
+	}
+	if w.paused {
+		w.recordingStatus = "PAUSED"
 	}
 
-	// Save and exit on Escape
-	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+	// Save and exit
+	if w.inputHandler.ActionIsJustPressed(ActionSaveAndQuit) {
 		if w.recording {
-			w.recorder.Stop()
-			log.Printf("Recording saved on exit: %s", w.recorder.GetOutputPath())
+			return w.finish(w.stopRecording())
+		}
+		return w.finish(nil)
+	}
+
+	return nil
+}
+
+// startRecording starts the recorder at the game's current layout size
+// If the size changes later, the recorder's resize policy applies
+func (w *GameWrapper) startRecording() error {
+	width, height := w.game.Layout(ebiten.WindowSize())
+	path := w.recorder.GetOutputPath()
+	if err := w.transform.check(width, height); err != nil {
+		return w.fail(&RecordingError{Op: "start", Path: path, Err: err})
+	}
+	if err := w.recorder.Start(width, height); err != nil {
+		return w.fail(&RecordingError{Op: "start", Path: path, Err: err})
+	}
+
+	w.recording = true
+	w.recordStart = time.Now()
+	if w.intro != nil {
+		d, err := w.captureCard(w.intro, width, height)
+		if err != nil {
+			return w.fail(&RecordingError{Op: "capture", Path: path, Err: err})
+		}
+		// The game's frames follow the intro on the video's timeline
+		w.recordStart = time.Now().Add(-d)
+	}
+	if w.events.onStart != nil {
+		w.events.onStart(path)
+	}
+	return nil
+}
+
+// stopRecording stops the recorder and finalizes the output file
+func (w *GameWrapper) stopRecording() error {
+	w.recording = false
+	path := w.recorder.GetOutputPath()
+	frames := w.recorder.FrameCount()
+	elapsed := time.Since(w.recordStart)
+	if w.events.onStop != nil {
+		w.events.onStop(frames, elapsed)
+	}
+	if w.outro != nil && w.recorder.IsRecording() {
+		width, height := w.game.Layout(ebiten.WindowSize())
+		if _, err := w.captureCard(w.outro, width, height); err != nil {
+			w.fail(&RecordingError{Op: "capture", Path: path, Err: err})
 		}
-		os.Exit(0)
 	}
 
+	if err := w.recorder.Stop(); err != nil {
+		return w.fail(&RecordingError{Op: "save", Path: path, Err: err})
+	}
+
+	if w.events.onSaved != nil {
+		w.events.onSaved(path, frames, elapsed)
+	}
 	return nil
 }
 
+// fail logs err, reports it through the OnError hook and returns it
+// Capture failures are logged by the recorder as dropped frames
+func (w *GameWrapper) fail(err *RecordingError) error {
+	if err.Op != "capture" {
+		w.logger.Error("recording failed", "op", err.Op, LogKeyPath, err.Path, "error", err.Err)
+	}
+	if w.events.onError != nil {
+		w.events.onError(err)
+	}
+	return err
+}
+
+// finish ends the wrapped game
+// The completion callback sees err, and Update returns ebiten.Termination
+// on success so RunGame returns nil, or err otherwise
+func (w *GameWrapper) finish(err error) error {
+	w.stopBot()
+	if w.onComplete != nil {
+		w.onComplete(err)
+	}
+	if err != nil {
+		return err
+	}
+	return ebiten.Termination
+}
+
 // Draw implements ebiten.Game.Draw
 func (w *GameWrapper) Draw(screen *ebiten.Image) {
 	// Call original game's Draw
 	w.game.Draw(screen)
 
+	// Screenshots are taken before the status overlay is drawn
+	if w.screenshotQueued {
+		w.screenshotQueued = false
+		if path, err := w.saveScreenshot(screen); err != nil {
+			w.logger.Error("screenshot failed", "error", err)
+		} else {
+			w.logger.Info("screenshot saved", LogKeyPath, path)
+		}
+	}
+
 	// Draw recording status overlay
 	if w.recordingStatus != "" {
 		ebitenutil.DebugPrintAt(screen, w.recordingStatus, 10, 10)
 	}
 
 	// Capture frame if recording
-	if w.recording {
-		if err := w.recorder.CaptureFrame(screen); err != nil {
-			log.Printf("Failed to capture frame: %v", err)
+	if w.recording && !w.paused {
+		w.captureFrames(screen)
+	}
+}
+
+// captureFrames captures as many frames as the recording is behind
+// The video's timeline follows the wall clock: frames drawn faster than
+// the recorder's FPS are skipped, and a slow frame is repeated, so the
+// video plays at real speed and stays in sync with recorded audio
+func (w *GameWrapper) captureFrames(screen *ebiten.Image) {
+	elapsed := time.Since(w.recordStart).Seconds()
+	due := int(elapsed*float64(w.recorder.FPS())) + 1 - w.recorder.FrameCount()
+	if due <= 0 {
+		return
+	}
+	frame := w.composite(screen)
+	for i := range due {
+		if i > 0 {
+			w.recorder.repeatNext()
+		}
+		if err := w.recorder.CaptureFrame(frame); err != nil {
+			w.fail(&RecordingError{Op: "capture", Path: w.recorder.GetOutputPath(), Err: err})
+			return
+		}
+		// The recorder stops itself at its frame limit
+		if !w.recorder.IsRecording() {
+			return
+		}
+		if w.events.onFrame != nil {
+			w.events.onFrame(w.recorder.FrameCount())
 		}
 	}
 }
 
+// composite returns screen with the video-only layers drawn on top
+// The layers go on an offscreen copy so the live screen stays clean
+func (w *GameWrapper) composite(screen *ebiten.Image) *ebiten.Image {
+	if len(w.layers) == 0 {
+		return screen
+	}
+	if w.frame == nil || w.frame.Bounds() != screen.Bounds() {
+		if w.frame != nil {
+			w.frame.Deallocate()
+		}
+		w.frame = ebiten.NewImage(screen.Bounds().Dx(), screen.Bounds().Dy())
+	}
+	w.frame.Clear()
+	w.frame.DrawImage(screen, nil)
+	for _, l := range w.layers {
+		l.draw(w.frame)
+	}
+	return w.frame
+}
+
+// saveScreenshot writes the screen as a PNG next to the recording output
+func (w *GameWrapper) saveScreenshot(screen *ebiten.Image) (string, error) {
+	w.screenshotCount++
+	out := w.recorder.GetOutputPath()
+	base := strings.TrimSuffix(out, filepath.Ext(out))
+	path := fmt.Sprintf("%s-shot%03d.png", base, w.screenshotCount)
+
+	bounds := screen.Bounds()
+	rgba := image.NewRGBA(bounds)
+	screen.ReadPixels(rgba.Pix)
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if err := png.Encode(f, rgba); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// defaultFPS reads RECORD_FPS, the recording's frame rate (default 30)
+func defaultFPS() (int, error) {
+	s := os.Getenv("RECORD_FPS")
+	if s == "" {
+		return 30, nil
+	}
+	fps, err := strconv.Atoi(s)
+	if err != nil || fps <= 0 {
+		return 30, fmt.Errorf("recorder: invalid fps %q", s)
+	}
+	return fps, nil
+}
+
+// applyWindowSize resizes the window to RECORD_WINDOW_SIZE, if set
+// WrapGame runs before ebiten.RunGame, so this wins over the game's own
+// SetWindowSize
+func applyWindowSize() error {
+	s := os.Getenv("RECORD_WINDOW_SIZE")
+	if s == "" {
+		return nil
+	}
+	size, err := ParseSize(s)
+	if err != nil {
+		return err
+	}
+	ebiten.SetWindowSize(size.X, size.Y)
+	return nil
+}
+
 // Layout implements ebiten.Game.Layout
 func (w *GameWrapper) Layout(outsideWidth, outsideHeight int) (int, int) {
 	return w.game.Layout(outsideWidth, outsideHeight)
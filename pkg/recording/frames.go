@@ -0,0 +1,113 @@
+package recording
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"time"
+
+	"golang.org/x/image/draw"
+)
+
+// FrameAt returns a copy of the frame shown at t
+func FrameAt(src Source, t time.Duration) (image.Image, time.Duration, error) {
+	if err := src.SkipTo(t); err != nil {
+		return nil, 0, err
+	}
+	img, start, _, err := src.Next()
+	if err == io.EOF {
+		return nil, 0, fmt.Errorf("recording: %s is past the end (%s)", t, src.Duration())
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return Copy(img), start, nil
+}
+
+// Pick returns a copy of the most visually varied of n evenly spaced
+// frames, the one with the highest Entropy, with its time and score
+// Title cards, fades and mostly empty screens score low
+func Pick(src Source, n int) (image.Image, time.Duration, float64, error) {
+	total := src.Duration()
+	var best image.Image
+	var bestT time.Duration
+	bestScore := -1.0
+	for i := 0; i < n; i++ {
+		// The middle of each of n equal spans, so never the very first or
+		// last frame
+		want := time.Duration((float64(i) + 0.5) / float64(n) * float64(total))
+		if err := src.SkipTo(want); err != nil {
+			return nil, 0, 0, err
+		}
+		img, t, _, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		if score := Entropy(img); score > bestScore {
+			best, bestT, bestScore = Copy(img), t, score
+		}
+	}
+	if best == nil {
+		return nil, 0, 0, errors.New("recording: no frames")
+	}
+	return best, bestT, bestScore, nil
+}
+
+// Entropy is the Shannon entropy, in bits, of img's colors quantized to
+// 8 levels per channel: 0 for a single color, up to 9 when the 512
+// colors are equally common
+// Every other pixel in each direction is enough to rank frames
+func Entropy(img image.Image) float64 {
+	var hist [512]int
+	total := 0
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y += 2 {
+		for x := b.Min.X; x < b.Max.X; x += 2 {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			hist[(r>>13)<<6|(g>>13)<<3|bl>>13]++
+			total++
+		}
+	}
+	e := 0.0
+	for _, n := range hist {
+		if n > 0 {
+			p := float64(n) / float64(total)
+			e -= p * math.Log2(p)
+		}
+	}
+	return e
+}
+
+// Copy copies img, which a Source may reuse, into a new RGBA image at
+// the origin
+func Copy(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Copy(dst, image.Point{}, img, b, draw.Src, nil)
+	return dst
+}
+
+// Scale returns img scaled to width w, keeping the aspect ratio, as a
+// new image
+// Whole upscale factors use nearest-neighbour so pixel art stays sharp;
+// anything else uses Catmull-Rom
+func Scale(img image.Image, w int) *image.RGBA {
+	b := img.Bounds()
+	if w == b.Dx() {
+		return Copy(img)
+	}
+	factor := float64(w) / float64(b.Dx())
+	h := max(int(math.Round(float64(b.Dy())*factor)), 1)
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	var scaler draw.Interpolator = draw.CatmullRom
+	if w%b.Dx() == 0 {
+		scaler = draw.NearestNeighbor
+	}
+	scaler.Scale(dst, dst.Rect, img, b, draw.Src, nil)
+	return dst
+}
@@ -0,0 +1,315 @@
+// Package recording reads the frames of a recording whatever its format:
+// an MJPEG AVI, a GIF, an animated WebP or a sequence of PNGs
+package recording
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"main/pkg/avireader"
+	"main/pkg/webpanim"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Source yields a recording's frames in order
+type Source interface {
+	// Next returns the next frame, when it starts and how long it shows;
+	// it returns io.EOF after the last frame
+	// The image may be reused by the following call
+	Next() (image.Image, time.Duration, time.Duration, error)
+
+	// SkipTo moves past the frames that end by t, so Next returns the
+	// one shown at t; it never moves back
+	// AVI and PNG frames are skipped without decoding them
+	SkipTo(t time.Duration) error
+
+	// FPS is the average frame rate
+	FPS() float64
+
+	// Duration is the recording's length
+	Duration() time.Duration
+
+	Close() error
+}
+
+// Open opens an AVI, GIF or WebP file, told apart by its first bytes
+func Open(path string) (Source, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	var magic [12]byte
+	if _, err := io.ReadFull(f, magic[:]); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: too short for a recording", path)
+	}
+	var src Source
+	switch {
+	case bytes.HasPrefix(magic[:], []byte("GIF8")):
+		src, err = newGIFSource(f)
+		f.Close()
+	case string(magic[0:4]) == "RIFF" && string(magic[8:12]) == "AVI ":
+		f.Close()
+		var r *avireader.Reader
+		if r, err = avireader.Open(path); err == nil {
+			src = &aviSource{r: r}
+		}
+	case string(magic[0:4]) == "RIFF" && string(magic[8:12]) == "WEBP":
+		var d *webpanim.Decoder
+		if d, err = webpanim.NewDecoder(f, stat.Size()); err == nil {
+			src = &webpSource{d: d, f: f}
+		} else {
+			f.Close()
+		}
+	default:
+		f.Close()
+		return nil, fmt.Errorf("%s: not an AVI, GIF or WebP file", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return src, nil
+}
+
+// OpenPNGs opens the PNG files among files as frames shown at fps, in
+// name order
+func OpenPNGs(files []string, fps float64) (Source, error) {
+	var pngs []string
+	for _, f := range files {
+		if strings.EqualFold(filepath.Ext(f), ".png") {
+			pngs = append(pngs, f)
+		}
+	}
+	if len(pngs) == 0 {
+		return nil, errors.New("no PNG files")
+	}
+	if fps <= 0 {
+		return nil, errors.New("PNG frame rate must be positive")
+	}
+	sort.Strings(pngs)
+	return &pngSource{files: pngs, fps: fps}, nil
+}
+
+// aviSource reads an MJPEG AVI
+type aviSource struct {
+	r    *avireader.Reader
+	next int
+}
+
+func (s *aviSource) Next() (image.Image, time.Duration, time.Duration, error) {
+	if s.next >= s.r.Len() {
+		return nil, 0, 0, io.EOF
+	}
+	img, err := s.r.Frame(s.next)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	t := s.r.Time(s.next)
+	s.next++
+	return img, t, s.r.Time(s.next) - t, nil
+}
+
+func (s *aviSource) SkipTo(t time.Duration) error {
+	for s.next < s.r.Len() && s.r.Time(s.next+1) <= t {
+		s.next++
+	}
+	return nil
+}
+
+func (s *aviSource) FPS() float64            { return s.r.FPS }
+func (s *aviSource) Duration() time.Duration { return s.r.Duration() }
+func (s *aviSource) Close() error            { return s.r.Close() }
+
+// webpSource reads an animated or still WebP
+type webpSource struct {
+	d    *webpanim.Decoder
+	f    *os.File
+	next int
+	t    time.Duration
+}
+
+func (s *webpSource) Next() (image.Image, time.Duration, time.Duration, error) {
+	img, dur, err := s.d.Next()
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	t := s.t
+	s.t += dur
+	s.next++
+	return img, t, dur, nil
+}
+
+func (s *webpSource) SkipTo(t time.Duration) error {
+	// Frames are composited onto the last, so they all have to be drawn
+	for s.next < len(s.d.Frames) && s.t+s.d.Frames[s.next].Duration <= t {
+		if _, _, _, err := s.Next(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *webpSource) FPS() float64 {
+	return fpsOf(len(s.d.Frames), s.Duration())
+}
+
+func (s *webpSource) Duration() time.Duration {
+	var total time.Duration
+	for _, f := range s.d.Frames {
+		total += f.Duration
+	}
+	return total
+}
+
+func (s *webpSource) Close() error { return s.f.Close() }
+
+// gifSource composites a GIF's frames with their disposal methods
+type gifSource struct {
+	g      *gif.GIF
+	canvas *image.RGBA
+	prev   *image.RGBA // canvas before the last frame, for DisposalPrevious
+	next   int
+	t      time.Duration
+}
+
+func newGIFSource(r io.ReadSeeker) (*gifSource, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	g, err := gif.DecodeAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(g.Image) == 0 {
+		return nil, errors.New("GIF has no frames")
+	}
+	return &gifSource{g: g, canvas: image.NewRGBA(image.Rect(0, 0, g.Config.Width, g.Config.Height))}, nil
+}
+
+func (s *gifSource) Next() (image.Image, time.Duration, time.Duration, error) {
+	if s.next >= len(s.g.Image) {
+		return nil, 0, 0, io.EOF
+	}
+	if i := s.next - 1; i >= 0 && i < len(s.g.Disposal) {
+		b := s.g.Image[i].Bounds()
+		switch s.g.Disposal[i] {
+		case gif.DisposalBackground:
+			draw.Draw(s.canvas, b, image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			draw.Draw(s.canvas, b, s.prev, b.Min, draw.Src)
+		}
+	}
+	frame := s.g.Image[s.next]
+	if s.next < len(s.g.Disposal) && s.g.Disposal[s.next] == gif.DisposalPrevious {
+		if s.prev == nil {
+			s.prev = image.NewRGBA(s.canvas.Rect)
+		}
+		copy(s.prev.Pix, s.canvas.Pix)
+	}
+	draw.Draw(s.canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+
+	dur := s.delay(s.next)
+	t := s.t
+	s.t += dur
+	s.next++
+	return s.canvas, t, dur, nil
+}
+
+// delay returns how long frame i shows
+func (s *gifSource) delay(i int) time.Duration {
+	var dur time.Duration
+	if i < len(s.g.Delay) {
+		dur = time.Duration(s.g.Delay[i]) * 10 * time.Millisecond
+	}
+	if dur <= 0 {
+		dur = 100 * time.Millisecond // what browsers show a 0 delay as
+	}
+	return dur
+}
+
+func (s *gifSource) SkipTo(t time.Duration) error {
+	// Frames are composited onto the last, so they all have to be drawn
+	for s.next < len(s.g.Image) && s.t+s.delay(s.next) <= t {
+		if _, _, _, err := s.Next(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *gifSource) FPS() float64 {
+	return fpsOf(len(s.g.Image), s.Duration())
+}
+
+func (s *gifSource) Duration() time.Duration {
+	var total time.Duration
+	for i := range s.g.Image {
+		total += s.delay(i)
+	}
+	return total
+}
+
+func (s *gifSource) Close() error { return nil }
+
+// pngSource reads a PNG per frame
+type pngSource struct {
+	files []string
+	fps   float64
+	next  int
+}
+
+func (s *pngSource) Next() (image.Image, time.Duration, time.Duration, error) {
+	if s.next >= len(s.files) {
+		return nil, 0, 0, io.EOF
+	}
+	path := s.files[s.next]
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("%s: %w", path, err)
+	}
+	t := s.time(s.next)
+	s.next++
+	return img, t, time.Duration(float64(time.Second) / s.fps), nil
+}
+
+func (s *pngSource) SkipTo(t time.Duration) error {
+	for s.next < len(s.files) && s.time(s.next+1) <= t {
+		s.next++
+	}
+	return nil
+}
+
+func (s *pngSource) time(i int) time.Duration {
+	return time.Duration(float64(i) / s.fps * float64(time.Second))
+}
+
+func (s *pngSource) FPS() float64            { return s.fps }
+func (s *pngSource) Duration() time.Duration { return s.time(len(s.files)) }
+func (s *pngSource) Close() error            { return nil }
+
+// fpsOf is the average frame rate of n frames lasting total
+func fpsOf(n int, total time.Duration) float64 {
+	if total <= 0 {
+		return 30
+	}
+	return float64(n) / total.Seconds()
+}
@@ -0,0 +1,171 @@
+package riff
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrTruncated is returned when a chunk runs past the end of its parent
+// or of the file, e.g. because the writer never finalized it
+var ErrTruncated = errors.New("riff: chunk runs past the end of its parent")
+
+// Chunk is a chunk's header and where its data is
+type Chunk struct {
+	ID     string // four-character code, e.g. "avih", "LIST"
+	Size   uint32 // data size, without the pad byte
+	Offset int64  // offset of the data in the file
+
+	// Kind is a LIST's list type, e.g. "movi"; its children start 4 bytes
+	// into the data. An unfinalized LIST may have a Kind but a Size of 0
+	Kind string
+}
+
+// End returns the offset just past the chunk, pad byte included
+func (c Chunk) End() int64 {
+	return c.Offset + int64(c.Size) + int64(c.Size&1)
+}
+
+// File is a RIFF file read through an io.ReaderAt
+type File struct {
+	r io.ReaderAt
+
+	// Form is the RIFF form type, e.g. "AVI " or "WEBP"
+	Form string
+
+	// Size is the file size the RIFF header declares, its 8 bytes included
+	Size int64
+}
+
+// Open reads the RIFF header from r
+func Open(r io.ReaderAt) (*File, error) {
+	var hdr [12]byte
+	if _, err := r.ReadAt(hdr[:], 0); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if string(hdr[0:4]) != "RIFF" {
+		return nil, errors.New("riff: not a RIFF file")
+	}
+	return &File{
+		r:    r,
+		Form: string(hdr[8:12]),
+		Size: int64(binary.LittleEndian.Uint32(hdr[4:8])) + 8,
+	}, nil
+}
+
+// Chunks returns the top-level chunks
+// fileSize bounds the walk when the header's size is larger, as in a
+// file cut short, and replaces a header size of 0, as left by a writer
+// that never finalized the file; pass 0 to trust the header
+func (f *File) Chunks(fileSize int64) ([]Chunk, error) {
+	end := f.Size
+	if fileSize > 0 && (fileSize < end || end == 8) {
+		end = fileSize
+	}
+	return f.walk(12, end)
+}
+
+// List returns the chunks inside the LIST chunk c
+func (f *File) List(c Chunk) ([]Chunk, error) {
+	if c.ID != "LIST" || c.Size < 4 {
+		return nil, fmt.Errorf("riff: %s is not a LIST", c.ID)
+	}
+	return f.walk(c.Offset+4, c.Offset+int64(c.Size))
+}
+
+// Within returns the chunks nested in c's data after a header of skip
+// bytes, like the frame data of a WebP ANMF chunk
+func (f *File) Within(c Chunk, skip int64) ([]Chunk, error) {
+	return f.walk(c.Offset+skip, c.Offset+int64(c.Size))
+}
+
+// Each calls fn for every chunk inside the LIST chunk c without
+// collecting them, for lists as long as an AVI's movi
+func (f *File) Each(c Chunk, fn func(Chunk) error) error {
+	if c.ID != "LIST" || c.Size < 4 {
+		return fmt.Errorf("riff: %s is not a LIST", c.ID)
+	}
+	return f.each(c.Offset+4, c.Offset+int64(c.Size), fn)
+}
+
+// Find returns the first chunk with the given ID, or a LIST of the given
+// kind, among chunks
+func Find(chunks []Chunk, id string) (Chunk, bool) {
+	for _, c := range chunks {
+		if c.ID == id || (c.ID == "LIST" && c.Kind == id) {
+			return c, true
+		}
+	}
+	return Chunk{}, false
+}
+
+// Data reads the data of c
+func (f *File) Data(c Chunk) ([]byte, error) {
+	data := make([]byte, c.Size)
+	if _, err := f.r.ReadAt(data, c.Offset); err != nil {
+		if err == io.EOF {
+			err = ErrTruncated
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// ChunkAt reads the header of the chunk starting at offset
+func (f *File) ChunkAt(offset int64) (Chunk, error) {
+	var hdr [12]byte
+	n, err := f.r.ReadAt(hdr[:], offset)
+	if n < 8 {
+		if err == nil || err == io.EOF {
+			err = ErrTruncated
+		}
+		return Chunk{}, err
+	}
+	c := Chunk{
+		ID:     string(hdr[0:4]),
+		Size:   binary.LittleEndian.Uint32(hdr[4:8]),
+		Offset: offset + 8,
+	}
+	if c.ID == "LIST" && n == 12 {
+		c.Kind = string(hdr[8:12])
+	}
+	return c, nil
+}
+
+func (f *File) walk(start, end int64) ([]Chunk, error) {
+	var chunks []Chunk
+	err := f.each(start, end, func(c Chunk) error {
+		chunks = append(chunks, c)
+		return nil
+	})
+	return chunks, err
+}
+
+// each reads the chunks between start and end
+// A chunk that doesn't fit is cut to end and is the last one, followed
+// by ErrTruncated, so what's left of a cut-short file can still be read
+func (f *File) each(start, end int64, fn func(Chunk) error) error {
+	for at := start; at+8 <= end; {
+		c, err := f.ChunkAt(at)
+		if err != nil {
+			return err
+		}
+		if c.Offset+int64(c.Size) > end {
+			truncated := fmt.Errorf("%w: %q at %d", ErrTruncated, c.ID, at)
+			c.Size = uint32(end - c.Offset)
+			if err := fn(c); err != nil {
+				return err
+			}
+			return truncated
+		}
+		if err := fn(c); err != nil {
+			return err
+		}
+		at = c.End()
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package riff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// chunk encodes a chunk with its pad byte
+func chunk(id string, data []byte) []byte {
+	b := append([]byte(id), binary.LittleEndian.AppendUint32(nil, uint32(len(data)))...)
+	b = append(b, data...)
+	if len(data)%2 == 1 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+func list(kind string, chunks ...[]byte) []byte {
+	return chunk("LIST", append([]byte(kind), bytes.Join(chunks, nil)...))
+}
+
+// testFile is a RIFF file with an odd-sized chunk, whose pad byte the
+// next chunk follows, and a LIST of two chunks
+func testFile() []byte {
+	body := append([]byte("TEST"), chunk("odd ", []byte("abc"))...)
+	body = append(body, list("kids", chunk("one ", []byte("1")), chunk("two ", []byte("22")))...)
+	return chunk("RIFF", body)
+}
+
+func TestChunks(t *testing.T) {
+	data := testFile()
+	f, err := Open(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Form != "TEST" || f.Size != int64(len(data)) {
+		t.Errorf("form %q, size %d; want TEST, %d", f.Form, f.Size, len(data))
+	}
+	chunks, err := f.Chunks(int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 2 {
+		t.Fatalf("%d chunks, want 2", len(chunks))
+	}
+
+	odd, ok := Find(chunks, "odd ")
+	if !ok || odd.Size != 3 || odd.End() != odd.Offset+4 {
+		t.Errorf("odd chunk = %+v, want 3 bytes and a pad byte", odd)
+	}
+	if b, err := f.Data(odd); err != nil || string(b) != "abc" {
+		t.Errorf("Data = %q, %v; want abc", b, err)
+	}
+
+	kids, ok := Find(chunks, "kids")
+	if !ok || kids.ID != "LIST" || kids.Kind != "kids" {
+		t.Fatalf("kids list = %+v, %v", kids, ok)
+	}
+	inner, err := f.List(kids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	if err := f.Each(kids, func(c Chunk) error {
+		ids = append(ids, c.ID)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(inner) != 2 || len(ids) != 2 || inner[1].ID != "two " || ids[1] != "two " {
+		t.Errorf("List = %+v, Each saw %q; want one and two", inner, ids)
+	}
+
+	at, err := f.ChunkAt(kids.Offset - 8)
+	if err != nil || at != kids {
+		t.Errorf("ChunkAt = %+v, %v; want %+v", at, err, kids)
+	}
+	if _, err := f.List(odd); err == nil {
+		t.Error("List of a plain chunk succeeded")
+	}
+}
+
+func TestTruncated(t *testing.T) {
+	data := testFile()
+	// Cut inside the list's second chunk
+	cut := data[:len(data)-1]
+	f, err := Open(bytes.NewReader(cut))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks, err := f.Chunks(int64(len(cut)))
+	if !errors.Is(err, ErrTruncated) {
+		t.Fatalf("Chunks err = %v, want ErrTruncated", err)
+	}
+	// The cut chunk is still returned, ending with the file
+	kids, ok := Find(chunks, "kids")
+	if !ok || kids.Offset+int64(kids.Size) != int64(len(cut)) {
+		t.Fatalf("kids list = %+v, want it cut to the file's end", kids)
+	}
+	var last Chunk
+	err = f.Each(kids, func(c Chunk) error {
+		last = c
+		return nil
+	})
+	if !errors.Is(err, ErrTruncated) || last.ID != "two " || last.Size != 1 {
+		t.Errorf("Each ended with %+v, %v; want two cut to 1 byte and ErrTruncated", last, err)
+	}
+}
+
+func TestUnfinalized(t *testing.T) {
+	// A writer that never finalized the file leaves its size at 0
+	data := testFile()
+	binary.LittleEndian.PutUint32(data[4:], 0)
+	f, err := Open(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks, err := f.Chunks(int64(len(data)))
+	if err != nil || len(chunks) != 2 {
+		t.Errorf("Chunks = %d chunks, %v; want 2", len(chunks), err)
+	}
+}
+
+func TestNotRIFF(t *testing.T) {
+	if _, err := Open(bytes.NewReader([]byte("RIFX\x00\x00\x00\x00TEST"))); err == nil {
+		t.Error("Open succeeded on a RIFX file")
+	}
+	if _, err := Open(bytes.NewReader([]byte("RIFF"))); err == nil {
+		t.Error("Open succeeded on a short file")
+	}
+}
@@ -0,0 +1,56 @@
+// Package settings parses the setting values the recorder, bot scripts
+// and profiles share, and needs no ebiten, so tools that only read
+// settings build without it
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Size presets ParseSize accepts by name
+var (
+	Preset720p  = image.Pt(1280, 720)
+	Preset1080p = image.Pt(1920, 1080)
+)
+
+// ParseSize parses "720p", "1080p" or "WIDTHxHEIGHT"
+func ParseSize(s string) (image.Point, error) {
+	switch strings.ToLower(s) {
+	case "720p":
+		return Preset720p, nil
+	case "1080p":
+		return Preset1080p, nil
+	}
+	ws, hs, ok := strings.Cut(strings.ToLower(s), "x")
+	w, werr := strconv.Atoi(ws)
+	h, herr := strconv.Atoi(hs)
+	if !ok || werr != nil || herr != nil || w <= 0 || h <= 0 {
+		return image.Point{}, fmt.Errorf("invalid size %q (want 720p, 1080p or WxH)", s)
+	}
+	return image.Pt(w, h), nil
+}
+
+// Duration is a time.Duration written as a string such as "1.5s" in JSON
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
@@ -0,0 +1,46 @@
+package settings
+
+import (
+	"encoding/json"
+	"image"
+	"testing"
+	"time"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		s    string
+		want image.Point
+		ok   bool
+	}{
+		{"720p", Preset720p, true},
+		{"1080P", Preset1080p, true},
+		{"640x480", image.Pt(640, 480), true},
+		{"800X600", image.Pt(800, 600), true},
+		{"640", image.Point{}, false},
+		{"0x480", image.Point{}, false},
+		{"-640x480", image.Point{}, false},
+		{"", image.Point{}, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.s)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("ParseSize(%q) = %v, %v; want %v, ok %v", tt.s, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestDurationJSON(t *testing.T) {
+	var d Duration
+	if err := json.Unmarshal([]byte(`"1.5s"`), &d); err != nil || time.Duration(d) != 1500*time.Millisecond {
+		t.Errorf("unmarshaled %v, %v; want 1.5s", time.Duration(d), err)
+	}
+	if data, err := json.Marshal(Duration(90 * time.Second)); err != nil || string(data) != `"1m30s"` {
+		t.Errorf("marshaled %s, %v; want \"1m30s\"", data, err)
+	}
+	for _, bad := range []string{`"soon"`, `15`} {
+		if err := json.Unmarshal([]byte(bad), &d); err == nil {
+			t.Errorf("unmarshaled %s without an error", bad)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package webpanim
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"main/pkg/riff"
+	"time"
+
+	"golang.org/x/image/webp"
+)
+
+// VP8X feature flags
+const (
+	flagAnimation = 0x02
+	flagAlpha     = 0x10
+)
+
+// ANMF frame flags
+const (
+	frameDispose = 0x01 // clear the frame's area to the background after showing it
+	frameNoBlend = 0x02 // replace the canvas pixels instead of alpha-blending
+)
+
+// ErrNotWebP is returned for input that isn't a RIFF WEBP file
+var ErrNotWebP = errors.New("webpanim: not a WebP file")
+
+// Frame is one ANMF chunk's header
+type Frame struct {
+	Rect     image.Rectangle // on the canvas
+	Duration time.Duration
+	Blend    bool // alpha-blend onto the canvas, else replace
+	Dispose  bool // clear Rect after the frame was shown
+
+	data riff.Chunk
+}
+
+// Decoder composites the frames of an animated WebP one at a time, so a
+// long recording doesn't have to fit in memory as RGBA
+// A still WebP decodes as a single frame
+type Decoder struct {
+	Width, Height int
+
+	// LoopCount is the number of times to play the animation; 0 is forever
+	LoopCount int
+
+	// Background is the ANIM background color; like libwebp's decoder,
+	// the canvas starts and is disposed to transparent regardless
+	Background color.NRGBA
+
+	// Frames are the frame headers, in order
+	Frames []Frame
+
+	r      io.ReaderAt
+	size   int64
+	file   *riff.File
+	still  bool
+	next   int
+	canvas *image.NRGBA
+}
+
+// NewDecoder reads the headers of the WebP in r
+func NewDecoder(r io.ReaderAt, size int64) (*Decoder, error) {
+	f, err := riff.Open(r)
+	if err != nil || f.Form != "WEBP" {
+		return nil, ErrNotWebP
+	}
+	chunks, err := f.Chunks(size)
+	if err != nil {
+		return nil, fmt.Errorf("webpanim: %w", err)
+	}
+	d := &Decoder{r: r, size: size, file: f}
+
+	vp8x, ok := riff.Find(chunks, "VP8X")
+	if ok {
+		hdr, err := f.Data(vp8x)
+		if err != nil {
+			return nil, fmt.Errorf("webpanim: %w", err)
+		}
+		if len(hdr) < 10 {
+			return nil, errors.New("webpanim: VP8X chunk too short")
+		}
+		d.Width, d.Height = u24(hdr[4:])+1, u24(hdr[7:])+1
+		ok = hdr[0]&flagAnimation != 0
+	}
+	if !ok {
+		// A still image: decode the whole file as one frame
+		cfg, err := webp.DecodeConfig(io.NewSectionReader(r, 0, size))
+		if err != nil {
+			return nil, fmt.Errorf("webpanim: %w", err)
+		}
+		d.Width, d.Height, d.still = cfg.Width, cfg.Height, true
+		d.Frames = []Frame{{Rect: image.Rect(0, 0, cfg.Width, cfg.Height)}}
+		return d, nil
+	}
+
+	if anim, ok := riff.Find(chunks, "ANIM"); ok {
+		data, err := f.Data(anim)
+		if err != nil {
+			return nil, fmt.Errorf("webpanim: %w", err)
+		}
+		if len(data) < 6 {
+			return nil, errors.New("webpanim: ANIM chunk too short")
+		}
+		d.Background = color.NRGBA{R: data[2], G: data[1], B: data[0], A: data[3]}
+		d.LoopCount = int(binary.LittleEndian.Uint16(data[4:6]))
+	}
+	canvas := image.Rect(0, 0, d.Width, d.Height)
+	for _, c := range chunks {
+		if c.ID != "ANMF" {
+			continue
+		}
+		if c.Size < 16 {
+			return nil, fmt.Errorf("webpanim: frame %d: ANMF chunk too short", len(d.Frames))
+		}
+		hdr, err := f.Data(riff.Chunk{ID: c.ID, Size: 16, Offset: c.Offset})
+		if err != nil {
+			return nil, fmt.Errorf("webpanim: %w", err)
+		}
+		x, y := u24(hdr[0:])*2, u24(hdr[3:])*2
+		frame := Frame{
+			Rect:     image.Rect(x, y, x+u24(hdr[6:])+1, y+u24(hdr[9:])+1),
+			Duration: time.Duration(u24(hdr[12:])) * time.Millisecond,
+			Blend:    hdr[15]&frameNoBlend == 0,
+			Dispose:  hdr[15]&frameDispose != 0,
+			data:     c,
+		}
+		if !frame.Rect.In(canvas) {
+			return nil, fmt.Errorf("webpanim: frame %d %v is outside the %dx%d canvas", len(d.Frames), frame.Rect, d.Width, d.Height)
+		}
+		d.Frames = append(d.Frames, frame)
+	}
+	if len(d.Frames) == 0 {
+		return nil, errors.New("webpanim: animation has no frames")
+	}
+	return d, nil
+}
+
+// Next composites the next frame and returns the canvas and how long it
+// shows; it returns io.EOF after the last frame
+// The canvas is reused: it's only valid until the next call
+func (d *Decoder) Next() (*image.NRGBA, time.Duration, error) {
+	if d.next >= len(d.Frames) {
+		return nil, 0, io.EOF
+	}
+	i := d.next
+	frame := d.Frames[i]
+	if d.canvas == nil {
+		d.canvas = image.NewNRGBA(image.Rect(0, 0, d.Width, d.Height))
+	} else if prev := d.Frames[i-1]; prev.Dispose {
+		draw.Draw(d.canvas, prev.Rect, image.Transparent, image.Point{}, draw.Src)
+	}
+
+	img, err := d.decodeFrame(frame)
+	if err != nil {
+		return nil, 0, fmt.Errorf("webpanim: frame %d: %w", i, err)
+	}
+	if img.Bounds().Size() != frame.Rect.Size() {
+		return nil, 0, fmt.Errorf("webpanim: frame %d is %v, its header says %v", i, img.Bounds().Size(), frame.Rect.Size())
+	}
+	op := draw.Over
+	if !frame.Blend {
+		op = draw.Src
+	}
+	draw.Draw(d.canvas, frame.Rect, img, img.Bounds().Min, op)
+	d.next++
+	return d.canvas, frame.Duration, nil
+}
+
+// decodeFrame decodes a frame's bitstream with x/image/webp, which only
+// reads single images: the ALPH, VP8 or VP8L chunks are wrapped in a
+// WebP file of their own
+func (d *Decoder) decodeFrame(frame Frame) (image.Image, error) {
+	if d.still {
+		return webp.Decode(io.NewSectionReader(d.r, 0, d.size))
+	}
+	data, err := d.file.Data(frame.data)
+	if err != nil {
+		return nil, err
+	}
+	chunks, err := d.file.Within(frame.data, 16)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF\x00\x00\x00\x00WEBP")
+	if _, ok := riff.Find(chunks, "ALPH"); ok {
+		// Alpha plus lossy data needs the extended format
+		buf.WriteString("VP8X")
+		buf.Write(binary.LittleEndian.AppendUint32(nil, 10))
+		buf.Write([]byte{flagAlpha, 0, 0, 0})
+		buf.Write(put24(frame.Rect.Dx() - 1))
+		buf.Write(put24(frame.Rect.Dy() - 1))
+	}
+	buf.Write(data[16:])
+	file := buf.Bytes()
+	binary.LittleEndian.PutUint32(file[4:8], uint32(len(file)-8))
+	return webp.Decode(bytes.NewReader(file))
+}
+
+// DecodeAll decodes every frame into its own image
+// Each frame is a full canvas, so this needs Width*Height*4 bytes per
+// frame; use a Decoder for long animations
+func DecodeAll(r io.ReaderAt, size int64) ([]*image.NRGBA, []time.Duration, error) {
+	d, err := NewDecoder(r, size)
+	if err != nil {
+		return nil, nil, err
+	}
+	frames := make([]*image.NRGBA, 0, len(d.Frames))
+	durations := make([]time.Duration, 0, len(d.Frames))
+	for {
+		canvas, dur, err := d.Next()
+		if err == io.EOF {
+			return frames, durations, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		frame := image.NewNRGBA(canvas.Rect)
+		copy(frame.Pix, canvas.Pix)
+		frames = append(frames, frame)
+		durations = append(durations, dur)
+	}
+}
+
+func u24(b []byte) int {
+	return int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+}
+
+func put24(v int) []byte {
+	return []byte{byte(v), byte(v >> 8), byte(v >> 16)}
+}
@@ -0,0 +1,134 @@
+package webpanim
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/HugoSmits86/nativewebp"
+)
+
+// recordWebP encodes frames the way WebPRecorder does: mapped to the
+// Plan9 palette and written as a lossless animation
+func recordWebP(t *testing.T, frames []image.Image, durations []uint) []byte {
+	t.Helper()
+	paletted := make([]image.Image, len(frames))
+	for i, f := range frames {
+		p := image.NewPaletted(f.Bounds(), palette.Plan9)
+		draw.Draw(p, f.Bounds(), f, f.Bounds().Min, draw.Src)
+		paletted[i] = p
+	}
+	var buf bytes.Buffer
+	animation := &nativewebp.Animation{
+		Images:    paletted,
+		Durations: durations,
+		Disposals: make([]uint, len(paletted)),
+	}
+	if err := nativewebp.EncodeAll(&buf, animation, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// testFrame is a frame of Plan9 colors, which survive the palette exactly:
+// a background with a square that moves with i
+func testFrame(i int) *image.RGBA {
+	bg := palette.Plan9[(i*37+5)%len(palette.Plan9)].(color.RGBA)
+	fg := palette.Plan9[(i*91+200)%len(palette.Plan9)].(color.RGBA)
+	img := image.NewRGBA(image.Rect(0, 0, 40, 30))
+	for y := range 30 {
+		for x := range 40 {
+			c := bg
+			if x >= i*8 && x < i*8+10 && y >= 10 && y < 20 {
+				c = fg
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestDecodeRecording(t *testing.T) {
+	durations := []uint{33, 50, 100, 17}
+	want := make([]image.Image, len(durations))
+	for i := range want {
+		want[i] = testFrame(i)
+	}
+	data := recordWebP(t, want, durations)
+
+	d, err := NewDecoder(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Width != 40 || d.Height != 30 {
+		t.Errorf("size = %dx%d, want 40x30", d.Width, d.Height)
+	}
+	if len(d.Frames) != len(want) {
+		t.Fatalf("%d frame headers, want %d", len(d.Frames), len(want))
+	}
+
+	for i := range want {
+		got, dur, err := d.Next()
+		if err != nil {
+			t.Fatalf("frame %d: %v", i, err)
+		}
+		if wantDur := time.Duration(durations[i]) * time.Millisecond; dur != wantDur {
+			t.Errorf("frame %d: duration %v, want %v", i, dur, wantDur)
+		}
+		if x, y, ok := samePixels(got, want[i]); !ok {
+			t.Errorf("frame %d: pixel (%d, %d) is %v, want %v", i, x, y, got.At(x, y), want[i].At(x, y))
+		}
+	}
+	if _, _, err := d.Next(); err != io.EOF {
+		t.Errorf("after the last frame: %v, want io.EOF", err)
+	}
+}
+
+func TestDecodeAll(t *testing.T) {
+	want := []image.Image{testFrame(0), testFrame(1)}
+	data := recordWebP(t, want, []uint{40, 40})
+
+	frames, durations, err := DecodeAll(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != len(want) || len(durations) != len(want) {
+		t.Fatalf("%d frames and %d durations, want %d", len(frames), len(durations), len(want))
+	}
+	// Every frame is a copy, not the reused canvas
+	for i := range want {
+		if x, y, ok := samePixels(frames[i], want[i]); !ok {
+			t.Errorf("frame %d: pixel (%d, %d) is %v, want %v", i, x, y, frames[i].At(x, y), want[i].At(x, y))
+		}
+	}
+}
+
+func TestNotWebP(t *testing.T) {
+	data := []byte("RIFF\x04\x00\x00\x00AVI ")
+	if _, err := NewDecoder(bytes.NewReader(data), int64(len(data))); !errors.Is(err, ErrNotWebP) {
+		t.Errorf("err = %v, want ErrNotWebP", err)
+	}
+}
+
+// samePixels compares two images' colors, returning the first pixel that
+// differs
+func samePixels(a, b image.Image) (x, y int, ok bool) {
+	if a.Bounds() != b.Bounds() {
+		return 0, 0, false
+	}
+	r := a.Bounds()
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if color.NRGBAModel.Convert(a.At(x, y)) != color.NRGBAModel.Convert(b.At(x, y)) {
+				return x, y, false
+			}
+		}
+	}
+	return 0, 0, true
+}